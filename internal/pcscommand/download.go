@@ -1,17 +1,17 @@
 package pcscommand
 
 import (
-	"container/list"
 	"fmt"
 	"github.com/iikira/BaiduPCS-Go/baidupcs"
+	"github.com/iikira/BaiduPCS-Go/internal/pcscommand/taskstore"
 	"github.com/iikira/BaiduPCS-Go/internal/pcsconfig"
 	"github.com/iikira/BaiduPCS-Go/pcsutil"
 	"github.com/iikira/BaiduPCS-Go/requester"
 	"github.com/iikira/BaiduPCS-Go/requester/downloader"
-	"github.com/iikira/BaiduPCS-Go/requester/rio"
 	"net/http/cookiejar"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -21,14 +21,32 @@ var (
 	DownloadSuffix = ".BaiduPCS-Go-downloading"
 )
 
-// dtask 下载任务
+// dtask 下载任务, 包装持久化的 taskstore.Task 记录
 type dtask struct {
-	ListTask
-	path         string                  // 下载的路径
-	downloadInfo *baidupcs.FileDirectory // 文件或目录详情
+	*taskstore.Task
+	downloadInfo *baidupcs.FileDirectory // 文件或目录详情, 仅在运行期间持有, 不落库
 }
 
-func getDownloadFunc(id int, savePath string, cfg *downloader.Config) baidupcs.DownloadFunc {
+// openTaskStore 打开下载任务队列的持久化存储
+func openTaskStore() (*taskstore.Store, error) {
+	return taskstore.Open(filepath.Join(pcsconfig.GetConfigDir(), "download_tasks.bolt"))
+}
+
+// pickFetcher 根据下载地址选择下载引擎:
+// magnet 链接和种子/metalink 文件优先交给用户配置的 aria2 RPC 服务;
+// 普通 http(s) 直链默认走内建的多线程下载引擎, 没有配置 aria2 时磁力/种子任务也交给内建引擎.
+func pickFetcher(downloadURL string, cfg *downloader.Config) downloader.Fetcher {
+	var aria2Cfg *downloader.Aria2Config
+	if pcsconfig.Config.Aria2RPCURL != "" {
+		aria2Cfg = &downloader.Aria2Config{
+			RPCURL: pcsconfig.Config.Aria2RPCURL,
+			Token:  pcsconfig.Config.Aria2Token,
+		}
+	}
+	return downloader.PickFetcher(downloadURL, aria2Cfg, pcsconfig.Config.PreferAria2, cfg)
+}
+
+func getDownloadFunc(id int, remotePath, savePath string, cfg *downloader.Config) baidupcs.DownloadFunc {
 	if cfg == nil {
 		cfg = downloader.NewConfig()
 	}
@@ -41,54 +59,56 @@ func getDownloadFunc(id int, savePath string, cfg *downloader.Config) baidupcs.D
 		h.SetKeepAlive(true)
 		h.SetTimeout(10 * time.Minute)
 
-		var (
-			file rio.WriteCloserAt
-			err  error
-		)
-
-		if !cfg.IsTest {
-			cfg.InstanceStatePath = savePath + DownloadSuffix
-			os.MkdirAll(filepath.Dir(savePath), 0777)
-			file, err = os.OpenFile(savePath, os.O_CREATE|os.O_WRONLY, 0777)
-			if err != nil {
-				return err
-			}
+		fetcher := pickFetcher(downloadURL, cfg)
+		if httpFetcher, ok := fetcher.(*downloader.HTTPFetcher); ok {
+			httpFetcher.SetClient(h)
 		}
 
-		download := downloader.NewDownloader(downloadURL, file, cfg)
-		download.SetClient(h)
-
-		exitDownloadFunc := make(chan struct{})
-		download.OnExecute(func() {
-			if cfg.IsTest {
-				fmt.Printf("[%d] 测试下载开始\n\n", id)
-			}
+		err := fetcher.Create(&downloader.FetchSpec{
+			URL:           downloadURL,
+			SavePath:      savePath,
+			RemotePath:    remotePath,
+			AccessToken:   pcsconfig.Config.AccessToken,
+			PreferOpenAPI: pcsconfig.Config.PreferOpenAPI,
+		})
+		if err != nil {
+			return err
+		}
 
-			ds := download.GetDownloadStatusChan()
-			for {
-				select {
-				case <-exitDownloadFunc:
-					return
-				case v, ok := <-ds:
-					if !ok { // channel 已经关闭
-						return
+		done := make(chan error, 1)
+		go func() {
+			taskID := strconv.Itoa(id)
+			for event := range fetcher.Events() {
+				downloader.DefaultNotifier.Publish(taskID, event)
+				switch event.Type {
+				case downloader.FetcherEventStarted:
+					if cfg.IsTest {
+						fmt.Printf("[%d] 测试下载开始\n\n", id)
 					}
-
+				case downloader.FetcherEventProgress:
+					v := event.Status
 					fmt.Printf("\r[%d] ↓ %s/%s %s/s in %s ............", id,
 						pcsutil.ConvertFileSize(v.Downloaded(), 2),
 						pcsutil.ConvertFileSize(v.TotalSize(), 2),
 						pcsutil.ConvertFileSize(v.SpeedsPerSecond(), 2),
 						v.TimeElapsed()/1e7*1e7,
 					)
+				case downloader.FetcherEventError:
+					done <- event.Err
+					return
+				case downloader.FetcherEventFinished:
+					done <- nil
+					return
 				}
 			}
-		})
+		}()
 
-		download.OnFinish(func() {
-			exitDownloadFunc <- struct{}{}
-		})
+		err = fetcher.Start()
+		if err != nil {
+			return err
+		}
 
-		err = download.Execute()
+		err = <-done
 		if err != nil {
 			return err
 		}
@@ -104,12 +124,15 @@ func getDownloadFunc(id int, savePath string, cfg *downloader.Config) baidupcs.D
 }
 
 // RunDownload 执行下载网盘内文件
-func RunDownload(isTest bool, parallel int, paths []string) {
+func RunDownload(isTest bool, parallel int, dynamicSplit bool, rateLimit int64, paths []string) {
 	// 设置下载配置
-	cfg := &downloader.Config{
-		IsTest:    isTest,
-		CacheSize: pcsconfig.Config.CacheSize,
-	}
+	cfg := downloader.NewConfig()
+	cfg.IsTest = isTest
+	cfg.CacheSize = pcsconfig.Config.CacheSize
+	// --dynamic-split 只能开启动态拆分, 不能覆盖配置文件里已经打开的设置
+	cfg.DynamicSplit = dynamicSplit || pcsconfig.Config.DynamicSplit
+	// 每个任务自己的限速, 和 download set-limit 设置的进程级全局限速互不影响, 取两者中更严格的一个
+	cfg.RateLimit = rateLimit
 
 	// 设置下载最大并发量
 	if parallel == 0 {
@@ -125,71 +148,87 @@ func RunDownload(isTest bool, parallel int, paths []string) {
 
 	fmt.Printf("\n")
 	fmt.Printf("[0] 提示: 当前下载最大并发量为: %d, 下载缓存为: %d\n", cfg.MaxParallel, cfg.CacheSize)
+	if cfg.DynamicSplit {
+		fmt.Println("[0] 提示: 已开启慢速分片动态拆分")
+	}
+	if cfg.RateLimit > 0 {
+		fmt.Printf("[0] 提示: 本批任务限速为: %s/s\n", pcsutil.ConvertFileSize(cfg.RateLimit, 2))
+	}
+
+	store, err := openTaskStore()
+	if err != nil {
+		fmt.Printf("打开下载任务队列失败, %s\n", err)
+		return
+	}
+	defer store.Close()
 
-	dlist := list.New()
-	lastID := 0
+	requeueInterruptedTasks(store)
 
 	for k := range paths {
-		lastID++
-		dlist.PushBack(&dtask{
-			ListTask: ListTask{
-				ID:       lastID,
-				MaxRetry: 3,
-			},
-			path: paths[k],
-		})
-		fmt.Printf("[%d] 加入下载队列: %s\n", lastID, paths[k])
+		task := &taskstore.Task{
+			Path:     paths[k],
+			Status:   taskstore.StatusReady,
+			MaxRetry: 3,
+		}
+		if err = store.Put(task); err != nil {
+			fmt.Printf("加入下载队列失败, %s, %s\n", paths[k], err)
+			continue
+		}
+		fmt.Printf("[%d] 加入下载队列: %s\n", task.ID, paths[k])
 	}
 
-	var (
-		e             *list.Element
-		task          *dtask
-		handleTaskErr = func(task *dtask, errManifest string, err error) {
-			if task == nil {
-				panic("task is nil")
-			}
-
-			if err == nil {
-				return
-			}
+	handleTaskErr := func(task *dtask, errManifest string, err error) {
+		if err == nil {
+			return
+		}
 
-			// 不重试的情况
-			switch {
-			case strings.Compare(errManifest, "下载文件错误") == 0 && strings.Contains(err.Error(), "文件已存在"):
-				fmt.Printf("[%d] %s, %s\n", task.ID, errManifest, err)
-				return
-			}
+		// 不重试的情况
+		switch {
+		case strings.Compare(errManifest, "下载文件错误") == 0 && strings.Contains(err.Error(), "文件已存在"):
+			fmt.Printf("[%d] %s, %s\n", task.ID, errManifest, err)
+			task.Status = taskstore.StatusDone
+			store.Put(task.Task)
+			return
+		}
 
-			fmt.Printf("[%d] %s, %s, 重试 %d/%d\n", task.ID, errManifest, err, task.retry, task.MaxRetry)
+		fmt.Printf("[%d] %s, %s, 重试 %d/%d\n", task.ID, errManifest, err, task.Retry, task.MaxRetry)
 
-			// 未达到失败重试最大次数, 将任务推送到队列末尾
-			if task.retry < task.MaxRetry {
-				task.retry++
-				dlist.PushBack(task)
-			}
-			time.Sleep(3 * time.Duration(task.retry) * time.Second)
+		// 未达到失败重试最大次数, 将任务状态改为 Ready, 等待下一轮被重新取出
+		task.ErrorMsg = err.Error()
+		if task.Retry < task.MaxRetry {
+			task.Retry++
+			task.Status = taskstore.StatusReady
+		} else {
+			task.Status = taskstore.StatusError
 		}
-		totalSize int64
-	)
+		store.Put(task.Task)
+		time.Sleep(3 * time.Duration(task.Retry) * time.Second)
+	}
+
+	var totalSize int64
 
 	for {
-		e = dlist.Front()
-		if e == nil { // 结束
+		storeTask, err := store.NextReady()
+		if err != nil {
+			fmt.Printf("读取下载任务队列失败, %s\n", err)
 			break
 		}
-
-		dlist.Remove(e) // 载入任务后, 移除队列
-
-		task = e.Value.(*dtask)
-		if task == nil {
-			continue
+		if storeTask == nil { // 队列已清空
+			break
 		}
 
+		task := &dtask{Task: storeTask}
+		task.Status = taskstore.StatusRunning
+		store.Put(task.Task)
+
 		if task.downloadInfo == nil {
-			task.downloadInfo, err = info.FilesDirectoriesMeta(task.path)
+			task.downloadInfo, err = info.FilesDirectoriesMeta(task.Path)
 			if err != nil {
 				// 不重试
 				fmt.Printf("[%d] 获取路径信息错误, %s\n", task.ID, err)
+				task.Status = taskstore.StatusError
+				task.ErrorMsg = err.Error()
+				store.Put(task.Task)
 				continue
 			}
 		}
@@ -197,53 +236,245 @@ func RunDownload(isTest bool, parallel int, paths []string) {
 		fmt.Printf("\n")
 		fmt.Printf("[%d] ----\n%s\n", task.ID, task.downloadInfo.String())
 
-		// 如果是一个目录, 将子文件和子目录加入队列
+		// 如果是一个目录, 将子文件和子目录以子任务的形式加入队列, 父任务标记完成
 		if task.downloadInfo.Isdir {
 			if !isTest { // 测试下载, 不建立空目录
-				os.MkdirAll(pcsconfig.GetSavePath(task.path), 0777) // 首先在本地创建目录, 保证空目录也能被保存
+				os.MkdirAll(pcsconfig.GetSavePath(task.Path), 0777) // 首先在本地创建目录, 保证空目录也能被保存
 			}
 
-			fileList, err := info.FilesDirectoriesList(task.path)
+			fileList, err := info.FilesDirectoriesList(task.Path)
 			if err != nil {
 				// 不重试
 				fmt.Printf("[%d] 获取目录信息错误, %s\n", task.ID, err)
+				task.Status = taskstore.StatusError
+				task.ErrorMsg = err.Error()
+				store.Put(task.Task)
 				continue
 			}
 
 			for k := range fileList {
-				lastID++
-				dlist.PushBack(&dtask{
-					ListTask: ListTask{
-						ID:       lastID,
-						MaxRetry: 3,
-					},
-					path:         fileList[k].Path,
-					downloadInfo: fileList[k],
-				})
-				fmt.Printf("[%d] 加入下载队列: %s\n", lastID, fileList[k].Path)
+				child := &taskstore.Task{
+					ParentID: task.ID,
+					Path:     fileList[k].Path,
+					Status:   taskstore.StatusReady,
+					MaxRetry: 3,
+				}
+				store.Put(child)
+				fmt.Printf("[%d] 加入下载队列: %s\n", child.ID, fileList[k].Path)
 			}
+
+			task.Status = taskstore.StatusDone
+			store.Put(task.Task)
 			continue
 		}
 
-		savePath := pcsconfig.GetSavePath(task.path)
-		fmt.Printf("[%d] 准备下载: %s\n\n", task.ID, task.path)
+		savePath := pcsconfig.GetSavePath(task.Path)
+		fmt.Printf("[%d] 准备下载: %s\n\n", task.ID, task.Path)
 		if !isTest && fileExist(savePath) {
 			fmt.Printf("[%d] 文件已经存在: %s, 跳过...\n", task.ID, savePath)
+			task.Status = taskstore.StatusDone
+			store.Put(task.Task)
 			continue
 		}
 
-		err = info.DownloadFile(task.path, getDownloadFunc(task.ID, savePath, cfg))
+		task.SavePath = savePath
+		err = info.DownloadFile(task.Path, getDownloadFunc(task.ID, task.Path, savePath, cfg))
 		if err != nil {
 			handleTaskErr(task, "下载文件错误", err)
 			continue
 		}
 
+		task.Status = taskstore.StatusDone
+		store.Put(task.Task)
 		totalSize += task.downloadInfo.Size
 	}
 
 	fmt.Printf("任务结束, 数据总量: %s\n", pcsutil.ConvertFileSize(totalSize))
 }
 
+// requeueInterruptedTasks 程序启动时, 把上次异常退出时处于 Running/Paused 的任务重新改为 Ready,
+// 断点续传依赖的 .BaiduPCS-Go-downloading 文件不受影响, 下载会从上次的字节位置继续.
+func requeueInterruptedTasks(store *taskstore.Store) {
+	resumable, err := store.ListResumable()
+	if err != nil {
+		fmt.Printf("读取待恢复任务失败, %s\n", err)
+		return
+	}
+
+	for _, task := range resumable {
+		fmt.Printf("[%d] 发现未完成的任务, 重新加入队列: %s\n", task.ID, task.Path)
+		task.Status = taskstore.StatusReady
+		store.Put(task)
+	}
+}
+
+// RunDownloadList 列出下载任务队列, statusFilter 为空表示列出所有状态
+func RunDownloadList(statusFilter string, page int) {
+	store, err := openTaskStore()
+	if err != nil {
+		fmt.Printf("打开下载任务队列失败, %s\n", err)
+		return
+	}
+	defer store.Close()
+
+	const pageSize = 20
+	tasks, err := store.List(taskstore.Status(statusFilter), page, pageSize)
+	if err != nil {
+		fmt.Printf("读取下载任务队列失败, %s\n", err)
+		return
+	}
+
+	if len(tasks) == 0 {
+		fmt.Println("没有符合条件的下载任务")
+		return
+	}
+
+	for _, task := range tasks {
+		fmt.Printf("%d\t%s\t%s\t%s/%s\t重试 %d/%d\n", task.ID, task.Status, task.Path,
+			pcsutil.ConvertFileSize(task.DownloadedSize, 2), pcsutil.ConvertFileSize(task.TotalSize, 2),
+			task.Retry, task.MaxRetry)
+	}
+}
+
+// RunDownloadRemove 从下载任务队列中删除一个任务
+func RunDownloadRemove(id int) {
+	store, err := openTaskStore()
+	if err != nil {
+		fmt.Printf("打开下载任务队列失败, %s\n", err)
+		return
+	}
+	defer store.Close()
+
+	if err = store.Delete(id); err != nil {
+		fmt.Printf("[%d] 删除下载任务失败, %s\n", id, err)
+		return
+	}
+	fmt.Printf("[%d] 已从下载任务队列中删除\n", id)
+}
+
+// RunDownloadRetry 将一个失败的下载任务重新放回队列
+func RunDownloadRetry(id int) {
+	store, err := openTaskStore()
+	if err != nil {
+		fmt.Printf("打开下载任务队列失败, %s\n", err)
+		return
+	}
+	defer store.Close()
+
+	task, err := store.Get(id)
+	if err != nil || task == nil {
+		fmt.Printf("[%d] 下载任务不存在\n", id)
+		return
+	}
+
+	task.Status = taskstore.StatusReady
+	task.ErrorMsg = ""
+	if err = store.Put(task); err != nil {
+		fmt.Printf("[%d] 重试下载任务失败, %s\n", id, err)
+		return
+	}
+	fmt.Printf("[%d] 已重新加入下载队列\n", id)
+}
+
+// RunDownloadResumeAll 将所有处于 Paused/Error 状态的任务重新放回队列
+func RunDownloadResumeAll() {
+	store, err := openTaskStore()
+	if err != nil {
+		fmt.Printf("打开下载任务队列失败, %s\n", err)
+		return
+	}
+	defer store.Close()
+
+	for _, status := range []taskstore.Status{taskstore.StatusPaused, taskstore.StatusError} {
+		tasks, err := store.List(status, 1, 0)
+		if err != nil {
+			fmt.Printf("读取下载任务队列失败, %s\n", err)
+			continue
+		}
+		for _, task := range tasks {
+			task.Status = taskstore.StatusReady
+			store.Put(task)
+			fmt.Printf("[%d] 已重新加入下载队列\n", task.ID)
+		}
+	}
+}
+
+// RunDownloadTorrent 通过 aria2 下载一个磁力链接, 或本地 .torrent/.metalink 文件,
+// 不经过网盘下载任务队列(BT/metalink 任务本来就不对应网盘内的路径, 没有父子任务/断点续传的概念).
+// target 以 .torrent/.metalink 结尾时视为本地文件, 其余(如 magnet 链接)按 URL 处理.
+func RunDownloadTorrent(target, savePath string) {
+	if pcsconfig.Config.Aria2RPCURL == "" {
+		fmt.Println("下载 BT/磁力/metalink 任务需要先在配置文件中设置 aria2 RPC 地址")
+		return
+	}
+	if savePath == "" {
+		savePath = pcsconfig.Config.SaveDir
+	}
+
+	spec := &downloader.FetchSpec{SavePath: savePath}
+	if strings.HasSuffix(target, ".torrent") || strings.HasSuffix(target, ".metalink") {
+		spec.FilePath = target
+	} else {
+		spec.URL = target
+	}
+
+	fetcher := downloader.NewAria2Fetcher(&downloader.Aria2Config{
+		RPCURL: pcsconfig.Config.Aria2RPCURL,
+		Token:  pcsconfig.Config.Aria2Token,
+	})
+
+	if err := fetcher.Create(spec); err != nil {
+		fmt.Println("创建下载任务失败,", err)
+		return
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		for event := range fetcher.Events() {
+			switch event.Type {
+			case downloader.FetcherEventStarted:
+				fmt.Println("任务已提交给 aria2")
+			case downloader.FetcherEventProgress:
+				v := event.Status
+				fmt.Printf("\r↓ %s/%s %s/s ............",
+					pcsutil.ConvertFileSize(v.Downloaded(), 2),
+					pcsutil.ConvertFileSize(v.TotalSize(), 2),
+					pcsutil.ConvertFileSize(v.SpeedsPerSecond(), 2),
+				)
+			case downloader.FetcherEventError:
+				done <- event.Err
+				return
+			case downloader.FetcherEventFinished:
+				done <- nil
+				return
+			}
+		}
+	}()
+
+	if err := fetcher.Start(); err != nil {
+		fmt.Println("启动下载任务失败,", err)
+		return
+	}
+
+	if err := <-done; err != nil {
+		fmt.Printf("\n\n下载失败, %s\n", err)
+		return
+	}
+	fmt.Printf("\n\n下载完成, 保存位置: %s\n", savePath)
+}
+
+// RunDownloadSetLimit 热更新全局下载限速, bytesPerSecond <= 0 表示取消限速.
+// 限速只作用于当前进程内的下载任务, 不会写入配置文件; 作为单独的 CLI 命令执行时,
+// 其它进程中正在进行的下载不受影响.
+func RunDownloadSetLimit(bytesPerSecond int64) {
+	downloader.SetGlobalRateLimit(bytesPerSecond)
+	if bytesPerSecond <= 0 {
+		fmt.Println("已取消下载限速")
+		return
+	}
+	fmt.Printf("下载限速已设置为: %s/s\n", pcsutil.ConvertFileSize(bytesPerSecond, 2))
+}
+
 // fileExist 检查文件是否存在,
 // 只有当文件存在, 断点续传文件不存在时, 才判断为存在
 func fileExist(path string) bool {
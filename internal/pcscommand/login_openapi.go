@@ -0,0 +1,25 @@
+package pcscommand
+
+import (
+	"fmt"
+
+	"github.com/iikira/BaiduPCS-Go/internal/pcsconfig"
+)
+
+// RunLoginByAccessToken 使用百度开放平台 access_token 登录, 对应 CLI 命令
+// `login --access-token=...`. 开放平台 access_token 不依赖 Cookie, 主要用来在
+// Cookie 下载被风控拦截时, 通过 OpenAPIResolver 提供一条备用下载通道.
+func RunLoginByAccessToken(accessToken string) {
+	if accessToken == "" {
+		fmt.Println("access_token 不能为空")
+		return
+	}
+
+	pcsconfig.Config.AccessToken = accessToken
+	if err := pcsconfig.Config.Save(); err != nil {
+		fmt.Printf("保存 access_token 失败, %s\n", err)
+		return
+	}
+
+	fmt.Println("access_token 登录成功, 下载时会作为开放平台下载源的备用通道")
+}
@@ -0,0 +1,213 @@
+// Package taskstore 提供下载任务的持久化存储, 用来取代重启即丢的
+// container/list.List 内存队列. 底层基于 BoltDB, 任务以 JSON 编码后
+// 按自增 ID 存入单个 bucket.
+package taskstore
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+// Status 下载任务状态
+type Status string
+
+const (
+	//StatusReady 任务已创建, 等待执行
+	StatusReady Status = "ready"
+	//StatusRunning 任务正在下载
+	StatusRunning Status = "running"
+	//StatusPaused 任务已暂停
+	StatusPaused Status = "paused"
+	//StatusError 任务出错
+	StatusError Status = "error"
+	//StatusDone 任务已完成
+	StatusDone Status = "done"
+)
+
+var tasksBucket = []byte("tasks")
+
+//Task 一条持久化的下载任务记录
+type Task struct {
+	ID             int
+	ParentID       int // 0 表示顶层任务, 非 0 表示由目录展开出的子任务
+	Path           string
+	SavePath       string
+	Status         Status
+	Retry          int
+	MaxRetry       int
+	TotalSize      int64
+	DownloadedSize int64
+	Speed          int64
+	GID            string // aria2 任务使用的 GID, 内建引擎任务留空
+	ErrorMsg       string
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+//Store 基于 BoltDB 的任务存储
+type Store struct {
+	db *bolt.DB
+}
+
+//Open 打开(或创建)位于 path 的任务存储
+func Open(path string) (*Store, error) {
+	db, err := bolt.Open(path, 0644, &bolt.Options{Timeout: 3 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(tasksBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &Store{db: db}, nil
+}
+
+//Close 关闭任务存储
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+//Put 新建或更新一条任务记录, task.ID 为 0 时分配新 ID
+func (s *Store) Put(task *Task) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(tasksBucket)
+
+		now := time.Now()
+		if task.ID == 0 {
+			id, _ := b.NextSequence()
+			task.ID = int(id)
+			task.CreatedAt = now
+		}
+		task.UpdatedAt = now
+
+		data, err := json.Marshal(task)
+		if err != nil {
+			return err
+		}
+		return b.Put(idKey(task.ID), data)
+	})
+}
+
+//Get 按 ID 读取一条任务记录
+func (s *Store) Get(id int) (*Task, error) {
+	var task *Task
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(tasksBucket)
+		data := b.Get(idKey(id))
+		if data == nil {
+			return nil
+		}
+		task = &Task{}
+		return json.Unmarshal(data, task)
+	})
+	return task, err
+}
+
+//Delete 删除一条任务记录
+func (s *Store) Delete(id int) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(tasksBucket).Delete(idKey(id))
+	})
+}
+
+//List 按状态过滤(statusFilter 为空表示不过滤)并分页返回任务, 按 UpdatedAt 倒序排列
+func (s *Store) List(statusFilter Status, page, pageSize int) ([]*Task, error) {
+	all, err := s.all()
+	if err != nil {
+		return nil, err
+	}
+
+	filtered := make([]*Task, 0, len(all))
+	for _, task := range all {
+		if statusFilter == "" || task.Status == statusFilter {
+			filtered = append(filtered, task)
+		}
+	}
+
+	sort.Slice(filtered, func(i, j int) bool {
+		return filtered[i].UpdatedAt.After(filtered[j].UpdatedAt)
+	})
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = len(filtered)
+	}
+
+	begin := (page - 1) * pageSize
+	if begin >= len(filtered) {
+		return []*Task{}, nil
+	}
+	end := begin + pageSize
+	if end > len(filtered) {
+		end = len(filtered)
+	}
+	return filtered[begin:end], nil
+}
+
+//ListResumable 返回所有处于 Running/Paused 状态的任务, 用于程序启动时重新入队
+func (s *Store) ListResumable() ([]*Task, error) {
+	all, err := s.all()
+	if err != nil {
+		return nil, err
+	}
+
+	resumable := make([]*Task, 0)
+	for _, task := range all {
+		if task.Status == StatusRunning || task.Status == StatusPaused {
+			resumable = append(resumable, task)
+		}
+	}
+	return resumable, nil
+}
+
+//NextReady 取出 ID 最小的一条 Ready 状态任务, 用于 FIFO 式的队列消费, 队列为空时返回 nil
+func (s *Store) NextReady() (*Task, error) {
+	all, err := s.all()
+	if err != nil {
+		return nil, err
+	}
+
+	var next *Task
+	for _, task := range all {
+		if task.Status != StatusReady {
+			continue
+		}
+		if next == nil || task.ID < next.ID {
+			next = task
+		}
+	}
+	return next, nil
+}
+
+func (s *Store) all() ([]*Task, error) {
+	tasks := make([]*Task, 0)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(tasksBucket).ForEach(func(k, v []byte) error {
+			task := &Task{}
+			if err := json.Unmarshal(v, task); err != nil {
+				return err
+			}
+			tasks = append(tasks, task)
+			return nil
+		})
+	})
+	return tasks, err
+}
+
+func idKey(id int) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(id))
+	return key
+}
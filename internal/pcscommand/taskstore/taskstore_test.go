@@ -0,0 +1,130 @@
+package taskstore
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func openTestStore(t *testing.T) *Store {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "tasks.bolt")
+	store, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestStorePutAssignsID(t *testing.T) {
+	store := openTestStore(t)
+
+	task := &Task{Path: "/a", Status: StatusReady}
+	if err := store.Put(task); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if task.ID == 0 {
+		t.Fatalf("Put() did not assign an ID")
+	}
+
+	got, err := store.Get(task.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got == nil || got.Path != "/a" {
+		t.Fatalf("Get() = %+v, want task with Path /a", got)
+	}
+}
+
+func TestStoreListFiltersByStatus(t *testing.T) {
+	store := openTestStore(t)
+
+	for i, status := range []Status{StatusReady, StatusDone, StatusReady, StatusError} {
+		task := &Task{Path: filepath.Join("/", string(rune('a'+i))), Status: status}
+		if err := store.Put(task); err != nil {
+			t.Fatalf("Put() error = %v", err)
+		}
+	}
+
+	ready, err := store.List(StatusReady, 1, 0)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(ready) != 2 {
+		t.Fatalf("List(StatusReady) returned %d tasks, want 2", len(ready))
+	}
+
+	all, err := store.List("", 1, 0)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(all) != 4 {
+		t.Fatalf("List(\"\") returned %d tasks, want 4", len(all))
+	}
+}
+
+func TestStoreListPaginates(t *testing.T) {
+	store := openTestStore(t)
+
+	for i := 0; i < 5; i++ {
+		task := &Task{Path: filepath.Join("/", string(rune('a'+i))), Status: StatusReady}
+		if err := store.Put(task); err != nil {
+			t.Fatalf("Put() error = %v", err)
+		}
+	}
+
+	page1, err := store.List(StatusReady, 1, 2)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(page1) != 2 {
+		t.Fatalf("page 1 has %d tasks, want 2", len(page1))
+	}
+
+	page3, err := store.List(StatusReady, 3, 2)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(page3) != 1 {
+		t.Fatalf("page 3 has %d tasks, want 1", len(page3))
+	}
+
+	page4, err := store.List(StatusReady, 4, 2)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(page4) != 0 {
+		t.Fatalf("page 4 has %d tasks, want 0 (out of range)", len(page4))
+	}
+}
+
+func TestStoreNextReadyReturnsSmallestID(t *testing.T) {
+	store := openTestStore(t)
+
+	var ids []int
+	for i := 0; i < 3; i++ {
+		task := &Task{Path: filepath.Join("/", string(rune('a'+i))), Status: StatusReady}
+		if err := store.Put(task); err != nil {
+			t.Fatalf("Put() error = %v", err)
+		}
+		ids = append(ids, task.ID)
+	}
+
+	// 把最先入队的任务标记为已完成, NextReady 应该跳过它, 返回下一个最小 ID 的 Ready 任务
+	first, err := store.Get(ids[0])
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	first.Status = StatusDone
+	if err := store.Put(first); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	next, err := store.NextReady()
+	if err != nil {
+		t.Fatalf("NextReady() error = %v", err)
+	}
+	if next == nil || next.ID != ids[1] {
+		t.Fatalf("NextReady() = %+v, want task %d", next, ids[1])
+	}
+}
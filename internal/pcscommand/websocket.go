@@ -0,0 +1,59 @@
+package pcscommand
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/websocket"
+	"github.com/iikira/BaiduPCS-Go/requester/downloader"
+)
+
+var wsUpgrader = websocket.Upgrader{
+	// 本地工具, 不校验来源, 只监听在用户自己指定的地址上
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// RunDownloadWebSocketServer 启动一个 WebSocket 服务, 每个连接一建立就会持续收到
+// 所有下载任务的事件(JSON 编码, 包含任务 ID, 事件类型, 进度和错误信息), 供未来的
+// Web UI 或外部监控程序实时展示进度, 不需要轮询.
+// 只能收到同一进程内发布到 downloader.DefaultNotifier 的事件, 其它进程中的下载任务不可见.
+func RunDownloadWebSocketServer(addr string) error {
+	http.HandleFunc("/ws/download", handleDownloadWS)
+	fmt.Printf("下载事件 WebSocket 服务已启动: ws://%s/ws/download\n", addr)
+	return http.ListenAndServe(addr, nil)
+}
+
+func handleDownloadWS(w http.ResponseWriter, r *http.Request) {
+	conn, err := wsUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	events, unsubscribe := downloader.DefaultNotifier.Subscribe("")
+	defer unsubscribe()
+
+	for event := range events {
+		// DlStatus 和 error 的字段都不导出, 直接编码 TaskEvent 会丢失进度和错误信息
+		payload := map[string]interface{}{
+			"TaskID": event.TaskID,
+			"Type":   event.Type,
+		}
+		if event.Status != nil {
+			payload["TotalSize"] = event.Status.TotalSize()
+			payload["Downloaded"] = event.Status.Downloaded()
+			payload["SpeedsPerSecond"] = event.Status.SpeedsPerSecond()
+		}
+		if event.Err != nil {
+			payload["Err"] = event.Err.Error()
+		}
+		data, err := json.Marshal(payload)
+		if err != nil {
+			continue
+		}
+		if err = conn.WriteMessage(websocket.TextMessage, data); err != nil {
+			return
+		}
+	}
+}
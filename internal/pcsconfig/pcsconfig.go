@@ -0,0 +1,92 @@
+// Package pcsconfig 管理 BaiduPCS-Go 的本地配置文件, 包括下载相关的参数
+// (并发数, 缓存大小, aria2/开放平台下载源等) 和本地保存目录.
+package pcsconfig
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+)
+
+//PCSConfig 本地配置
+type PCSConfig struct {
+	configFilePath string // 配置文件所在路径, 不参与序列化
+
+	UserAgent   string `json:"user_agent"`   // 下载/接口请求使用的 User-Agent
+	CacheSize   int    `json:"cache_size"`   // 下载缓存大小
+	MaxParallel int    `json:"max_parallel"` // 下载最大并发数
+	SaveDir     string `json:"save_dir"`     // 下载文件保存的根目录
+
+	// aria2 RPC 后端配置, 详见 requester/downloader.Aria2Config
+	Aria2RPCURL string `json:"aria2_rpc_url"`
+	Aria2Token  string `json:"aria2_token"`
+	PreferAria2 bool   `json:"prefer_aria2"` // true 时 http(s) 直链也优先交给 aria2
+
+	// 百度开放平台下载源配置, 详见 requester/downloader.OpenAPIResolver
+	AccessToken   string `json:"access_token"`
+	PreferOpenAPI bool   `json:"prefer_open_api"` // true 时优先尝试开放平台下载源
+
+	DynamicSplit bool `json:"dynamic_split"` // true 时对下载慢的分片动态拆分(work-stealing), 详见 requester/downloader.Config.DynamicSplit
+}
+
+//Config 进程内唯一的配置实例, 程序启动时由 init 加载
+var Config = &PCSConfig{
+	UserAgent:   "netdisk;5.2.2.2;PC;PC-Windows;10.0.19042;WindowsBaiduYunGuanJia",
+	CacheSize:   256 * 1024,
+	MaxParallel: 5,
+}
+
+func init() {
+	Config.configFilePath = defaultConfigFilePath()
+	Config.init()
+}
+
+// init 加载已有的配置文件(如果存在), 不存在时保持上面的默认值
+func (c *PCSConfig) init() {
+	data, err := ioutil.ReadFile(c.configFilePath)
+	if err != nil {
+		return
+	}
+	json.Unmarshal(data, c)
+}
+
+//Save 把当前配置写回配置文件
+func (c *PCSConfig) Save() error {
+	if err := os.MkdirAll(filepath.Dir(c.configFilePath), 0700); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(c.configFilePath, data, 0600)
+}
+
+// defaultConfigFilePath 返回配置文件默认路径: ~/.BaiduPCS-Go/pcs_config.json
+func defaultConfigFilePath() string {
+	return filepath.Join(GetConfigDir(), "pcs_config.json")
+}
+
+//GetConfigDir 返回配置文件所在目录: ~/.BaiduPCS-Go
+func GetConfigDir() string {
+	home := ""
+	if u, err := user.Current(); err == nil {
+		home = u.HomeDir
+	}
+	if home == "" {
+		home, _ = os.UserHomeDir()
+	}
+	return filepath.Join(home, ".BaiduPCS-Go")
+}
+
+//GetSavePath 把网盘内的路径转换为本地保存路径: SaveDir/pcsPath
+func GetSavePath(pcsPath string) string {
+	saveDir := Config.SaveDir
+	if saveDir == "" {
+		saveDir = filepath.Join(GetConfigDir(), "download")
+	}
+	return filepath.Join(saveDir, filepath.FromSlash(pcsPath))
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/iikira/BaiduPCS-Go/internal/pcscommand"
+	"github.com/urfave/cli"
+)
+
+func main() {
+	app := cli.NewApp()
+	app.Name = "BaiduPCS-Go"
+	app.Usage = "百度网盘命令行工具"
+
+	app.Commands = []cli.Command{
+		{
+			Name:  "login",
+			Usage: "登录百度账号",
+			Description: `
+	login --access-token=<access_token> 使用百度开放平台 access_token 登录,
+	不依赖 Cookie, 主要用于在 Cookie 下载被风控拦截时提供一条备用下载通道.`,
+			Category: "登录",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "access-token",
+					Usage: "百度开放平台 access_token",
+				},
+			},
+			Action: func(c *cli.Context) error {
+				accessToken := c.String("access-token")
+				if accessToken == "" {
+					cli.ShowCommandHelp(c, "login")
+					return nil
+				}
+				pcscommand.RunLoginByAccessToken(accessToken)
+				return nil
+			},
+		},
+		{
+			Name:      "download",
+			Aliases:   []string{"d"},
+			Usage:     "下载文件/目录",
+			ArgsUsage: "<网盘文件/目录路径 ...>",
+			Description: `
+	download 将一批网盘路径加入下载队列并立即开始下载.
+	下载任务会持久化到本地任务队列, 中途被打断的任务会在下次执行 download 时重新入队并断点续传;
+	暂停或出错的任务可通过 download resume-all 重新放回队列.`,
+			Category: "下载",
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "test",
+					Usage: "测试下载, 不保存文件到本地",
+				},
+				cli.IntFlag{
+					Name:  "p",
+					Usage: "指定下载最大并发量, 0 为使用默认配置",
+				},
+				cli.BoolFlag{
+					Name:  "dynamic-split",
+					Usage: "对下载慢的分片动态拆分(work-stealing), 也可在配置文件中长期开启",
+				},
+				cli.Int64Flag{
+					Name:  "rate-limit",
+					Usage: "限制本批任务的下载速度, 单位字节/秒, 0 为不限速, 与 download set-limit 的全局限速互不影响",
+				},
+			},
+			Action: func(c *cli.Context) error {
+				if c.NArg() == 0 {
+					cli.ShowCommandHelp(c, "download")
+					return nil
+				}
+				pcscommand.RunDownload(c.Bool("test"), c.Int("p"), c.Bool("dynamic-split"), c.Int64("rate-limit"), c.Args())
+				return nil
+			},
+			Subcommands: []cli.Command{
+				{
+					Name:  "ls",
+					Usage: "列出下载任务队列",
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "status",
+							Usage: "按状态筛选, 为空表示列出所有状态",
+						},
+						cli.IntFlag{
+							Name:  "page",
+							Value: 1,
+							Usage: "页码, 从 1 开始",
+						},
+					},
+					Action: func(c *cli.Context) error {
+						pcscommand.RunDownloadList(c.String("status"), c.Int("page"))
+						return nil
+					},
+				},
+				{
+					Name:      "rm",
+					Usage:     "删除一个下载任务",
+					ArgsUsage: "<任务ID>",
+					Action: func(c *cli.Context) error {
+						id, err := parseTaskID(c)
+						if err != nil {
+							return err
+						}
+						pcscommand.RunDownloadRemove(id)
+						return nil
+					},
+				},
+				{
+					Name:      "retry",
+					Usage:     "将一个失败的下载任务重新放回队列",
+					ArgsUsage: "<任务ID>",
+					Action: func(c *cli.Context) error {
+						id, err := parseTaskID(c)
+						if err != nil {
+							return err
+						}
+						pcscommand.RunDownloadRetry(id)
+						return nil
+					},
+				},
+				{
+					Name:  "resume-all",
+					Usage: "将所有暂停或出错的下载任务重新放回队列",
+					Action: func(c *cli.Context) error {
+						pcscommand.RunDownloadResumeAll()
+						return nil
+					},
+				},
+				{
+					Name:      "torrent",
+					Usage:     "通过 aria2 下载磁力链接/BT 种子/metalink 文件, 需先配置 aria2 RPC 地址",
+					ArgsUsage: "<磁力链接或本地 .torrent/.metalink 文件路径> [保存路径]",
+					Action: func(c *cli.Context) error {
+						if c.NArg() == 0 {
+							cli.ShowCommandHelp(c, "torrent")
+							return nil
+						}
+						pcscommand.RunDownloadTorrent(c.Args().Get(0), c.Args().Get(1))
+						return nil
+					},
+				},
+				{
+					Name:      "set-limit",
+					Usage:     "设置全局下载限速, 只作用于当前进程内的下载任务",
+					ArgsUsage: "<字节/秒, <=0 表示取消限速>",
+					Action: func(c *cli.Context) error {
+						if c.NArg() == 0 {
+							return fmt.Errorf("请指定限速值, 单位字节/秒")
+						}
+						var limit int64
+						if _, err := fmt.Sscanf(c.Args().First(), "%d", &limit); err != nil {
+							return fmt.Errorf("无效的限速值: %s", c.Args().First())
+						}
+						pcscommand.RunDownloadSetLimit(limit)
+						return nil
+					},
+				},
+			},
+		},
+		{
+			Name:      "serve-ws",
+			Usage:     "启动下载事件 WebSocket 服务, 供外部程序实时订阅同一进程内的下载进度",
+			ArgsUsage: "[监听地址]",
+			Category:  "下载",
+			Action: func(c *cli.Context) error {
+				addr := c.Args().First()
+				if addr == "" {
+					addr = "127.0.0.1:9888"
+				}
+				return pcscommand.RunDownloadWebSocketServer(addr)
+			},
+		},
+	}
+
+	if err := app.Run(os.Args); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+// parseTaskID 从命令行的第一个参数解析出下载任务 ID
+func parseTaskID(c *cli.Context) (int, error) {
+	if c.NArg() == 0 {
+		return 0, fmt.Errorf("请指定任务 ID")
+	}
+	var id int
+	if _, err := fmt.Sscanf(c.Args().First(), "%d", &id); err != nil {
+		return 0, fmt.Errorf("无效的任务 ID: %s", c.Args().First())
+	}
+	return id, nil
+}
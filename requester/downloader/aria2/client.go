@@ -0,0 +1,260 @@
+// Package aria2 实现了 aria2 JSON-RPC 协议的一个精简客户端,
+// 支持通过 HTTP 发起调用, 以及通过 WebSocket 接收任务通知.
+package aria2
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Client aria2 JSON-RPC 客户端
+type Client struct {
+	RPCURL string // 例如 http://127.0.0.1:6800/jsonrpc
+	Token  string // aria2 secret token, 为空则不携带
+
+	httpClient *http.Client
+	idSeq      uint64
+
+	wsConn     *websocket.Conn
+	wsMu       sync.Mutex
+	notifiers  map[string][]chan *Notification // gid -> 订阅者
+	notifierMu sync.Mutex
+}
+
+// Notification 表示 aria2 通过 WebSocket 推送的下载事件,
+// 如 aria2.onDownloadComplete / aria2.onDownloadError.
+type Notification struct {
+	Method string
+	GID    string
+}
+
+type rpcRequest struct {
+	JSONRPC string        `json:"jsonrpc"`
+	ID      string        `json:"id"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+type rpcResponse struct {
+	ID     string          `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *rpcError       `json:"error"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// NewClient 初始化一个 aria2 Client
+func NewClient(rpcURL, token string) *Client {
+	return &Client{
+		RPCURL:     rpcURL,
+		Token:      token,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		notifiers:  make(map[string][]chan *Notification),
+	}
+}
+
+func (c *Client) nextID() string {
+	return fmt.Sprintf("baidupcs-go-%d", atomic.AddUint64(&c.idSeq, 1))
+}
+
+// token 在 aria2 中以 "token:<secret>" 的形式作为第一个参数传入
+func (c *Client) paramsWithToken(params ...interface{}) []interface{} {
+	if c.Token == "" {
+		return params
+	}
+	return append([]interface{}{"token:" + c.Token}, params...)
+}
+
+func (c *Client) call(method string, params []interface{}, result interface{}) error {
+	reqBody := &rpcRequest{
+		JSONRPC: "2.0",
+		ID:      c.nextID(),
+		Method:  method,
+		Params:  c.paramsWithToken(params...),
+	}
+	b, err := json.Marshal(reqBody)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.httpClient.Post(c.RPCURL, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var rpcResp rpcResponse
+	if err = json.NewDecoder(resp.Body).Decode(&rpcResp); err != nil {
+		return err
+	}
+	if rpcResp.Error != nil {
+		return errors.New(rpcResp.Error.Message)
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(rpcResp.Result, result)
+}
+
+// AddURI 添加一个直链/磁力链接下载任务, 返回 aria2 分配的 GID
+func (c *Client) AddURI(uris []string, options map[string]string) (gid string, err error) {
+	uriList := make([]interface{}, len(uris))
+	for i, u := range uris {
+		uriList[i] = u
+	}
+	err = c.call("aria2.addUri", []interface{}{uriList, options}, &gid)
+	return
+}
+
+// AddTorrent 添加一个 BT 种子下载任务, torrent 为种子文件内容(base64编码前的原始字节会由调用方负责编码)
+func (c *Client) AddTorrent(torrentBase64 string, uris []string, options map[string]string) (gid string, err error) {
+	uriList := make([]interface{}, len(uris))
+	for i, u := range uris {
+		uriList[i] = u
+	}
+	err = c.call("aria2.addTorrent", []interface{}{torrentBase64, uriList, options}, &gid)
+	return
+}
+
+// AddMetalink 添加一个 metalink 下载任务, 返回其包含的所有任务 GID
+func (c *Client) AddMetalink(metalinkBase64 string, options map[string]string) (gids []string, err error) {
+	err = c.call("aria2.addMetalink", []interface{}{metalinkBase64, options}, &gids)
+	return
+}
+
+// Status aria2.tellStatus 返回的字段, 仅保留调用方关心的子集
+type Status struct {
+	GID             string `json:"gid"`
+	Status          string `json:"status"` // active, waiting, paused, error, complete, removed
+	TotalLength     string `json:"totalLength"`
+	CompletedLength string `json:"completedLength"`
+	DownloadSpeed   string `json:"downloadSpeed"`
+	ErrorMessage    string `json:"errorMessage"`
+	Files           []struct {
+		Path string `json:"path"`
+	} `json:"files"`
+}
+
+// TellStatus 查询任务状态
+func (c *Client) TellStatus(gid string) (*Status, error) {
+	var status Status
+	err := c.call("aria2.tellStatus", []interface{}{gid}, &status)
+	if err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// Pause 暂停任务
+func (c *Client) Pause(gid string) error {
+	return c.call("aria2.pause", []interface{}{gid}, nil)
+}
+
+// Unpause 恢复任务
+func (c *Client) Unpause(gid string) error {
+	return c.call("aria2.unpause", []interface{}{gid}, nil)
+}
+
+// Remove 取消任务
+func (c *Client) Remove(gid string) error {
+	return c.call("aria2.remove", []interface{}{gid}, nil)
+}
+
+// Subscribe 订阅某个 GID 的下载通知(onDownloadComplete/onDownloadError 等),
+// 首次调用会惰性建立 WebSocket 连接.
+func (c *Client) Subscribe(gid string) (<-chan *Notification, error) {
+	if err := c.ensureWS(); err != nil {
+		return nil, err
+	}
+
+	ch := make(chan *Notification, 1)
+	c.notifierMu.Lock()
+	c.notifiers[gid] = append(c.notifiers[gid], ch)
+	c.notifierMu.Unlock()
+	return ch, nil
+}
+
+func (c *Client) ensureWS() error {
+	c.wsMu.Lock()
+	defer c.wsMu.Unlock()
+	if c.wsConn != nil {
+		return nil
+	}
+
+	wsURL, err := toWebsocketURL(c.RPCURL)
+	if err != nil {
+		return err
+	}
+
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	if err != nil {
+		return err
+	}
+	c.wsConn = conn
+
+	go c.readNotifications(conn)
+	return nil
+}
+
+type wsNotification struct {
+	Method string `json:"method"`
+	Params []struct {
+		GID string `json:"gid"`
+	} `json:"params"`
+}
+
+func (c *Client) readNotifications(conn *websocket.Conn) {
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var n wsNotification
+		if err = json.Unmarshal(data, &n); err != nil {
+			continue
+		}
+		switch n.Method {
+		case "aria2.onDownloadComplete", "aria2.onDownloadError", "aria2.onDownloadStart",
+			"aria2.onDownloadPause", "aria2.onDownloadStop", "aria2.onBtDownloadComplete":
+		default:
+			continue
+		}
+		if len(n.Params) == 0 {
+			continue
+		}
+
+		gid := n.Params[0].GID
+		c.notifierMu.Lock()
+		for _, ch := range c.notifiers[gid] {
+			select {
+			case ch <- &Notification{Method: n.Method, GID: gid}:
+			default:
+			}
+		}
+		c.notifierMu.Unlock()
+	}
+}
+
+// toWebsocketURL 把 http(s):// 形式的 RPC 地址转换为 ws(s):// 形式
+func toWebsocketURL(rpcURL string) (string, error) {
+	switch {
+	case len(rpcURL) > 7 && rpcURL[:7] == "http://":
+		return "ws://" + rpcURL[7:], nil
+	case len(rpcURL) > 8 && rpcURL[:8] == "https://":
+		return "wss://" + rpcURL[8:], nil
+	default:
+		return "", fmt.Errorf("非法的 aria2 RPC 地址: %s", rpcURL)
+	}
+}
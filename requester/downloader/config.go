@@ -0,0 +1,32 @@
+package downloader
+
+//MinParallelSize 每个线程至少分配到的字节数, 低于这个值就不再增加并发数
+const MinParallelSize = 256 * 1024
+
+//Config 下载配置
+type Config struct {
+	IsTest            bool   // 测试下载, 不写入文件
+	CacheSize         int    // 每次从网络读取, 写入磁盘前的缓存大小
+	MaxParallel       int    // 最大并发线程数
+	InstanceStatePath string // 断点续传状态文件路径
+
+	RateLimit int64 // 当前任务的限速, 字节/秒, <= 0 表示不限速. 全局限速另见 SetGlobalRateLimit
+
+	DynamicSplit bool    // 是否开启慢速分片的动态拆分(work-stealing)
+	SlowFactor   float64 // 某个 Worker 的 ETA 超过中位数 ETA 的这个倍数时判定为"慢"
+	MinSplitSize int64   // 被拆分的 Worker 剩余字节数至少要达到这个值才会被拆分
+
+	parallel  int // 实际使用的并发线程数, 由 Execute 按 MaxParallel 和文件大小计算得出
+	cacheSize int // 实际使用的缓存大小, 由 Execute 按 CacheSize 和分片大小计算得出
+}
+
+//NewConfig 初始化默认下载配置
+func NewConfig() *Config {
+	return &Config{
+		CacheSize:    256 * 1024,
+		MaxParallel:  5,
+		DynamicSplit: false,
+		SlowFactor:   2,
+		MinSplitSize: 1024 * 1024,
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"github.com/iikira/BaiduPCS-Go/pcsverbose"
 	"github.com/iikira/BaiduPCS-Go/requester"
+	"github.com/iikira/BaiduPCS-Go/requester/ratelimit"
 	"github.com/iikira/BaiduPCS-Go/requester/rio"
 	"io"
 	"sync"
@@ -28,6 +29,55 @@ type Downloader struct {
 	config        *Config
 	monitor       *Monitor
 	instanceState *InstanceState
+
+	resolvers []SourceResolver // 候选下载地址解析器, 按顺序尝试, 详见 SourceResolver
+	sources   []*Source        // resolvers 解析出的候选地址, 用于多源并行下载
+}
+
+//SetResolvers 设置候选下载地址解析器, 按顺序尝试直到有一个解析出可用地址为止.
+//不调用此方法时, Downloader 的行为和重构前完全一致, 只使用 durl 单一地址下载.
+func (der *Downloader) SetResolvers(resolvers ...SourceResolver) {
+	der.resolvers = resolvers
+}
+
+//probeSource 用一次 HEAD 请求验证候选地址是否真的可用. Cookie 方式被风控拦截时,
+//Resolve 本身往往不会报错(它只是回显出之前已经拿到的直链), 拦截表现为这次探测请求
+//返回 4xx/5xx, 因此必须在这里实际探测一次, 才能让 OpenAPIResolver 在拦截发生时真正
+//生效, 而不是永远排在第一个"看起来能用"的 resolver 后面.
+func (der *Downloader) probeSource(source *Source) bool {
+	resp, err := der.client.Req("HEAD", source.URL, nil, source.Headers)
+	if resp != nil {
+		resp.Body.Close()
+	}
+	if err != nil {
+		return false
+	}
+	return resp.StatusCode/100 == 2
+}
+
+//resolveSources 依次尝试 der.resolvers, 返回第一个解析出可用(探测通过)地址的 resolver 的候选地址列表;
+//没有配置 resolvers 或全部解析失败时, 退化为只用 der.durl 这一个地址.
+func (der *Downloader) resolveSources() []*Source {
+	for _, resolver := range der.resolvers {
+		sources, err := resolver.Resolve(der.durl)
+		if err != nil || len(sources) == 0 {
+			continue
+		}
+
+		// 逐个探测, 而不是只看第一个候选: 某个镜像被拦截不代表其它镜像也不可用,
+		// 否则即使 resolver 返回了多个地址, 多源并行也会因为第一个凑巧失败而整体放弃.
+		alive := make([]*Source, 0, len(sources))
+		for _, source := range sources {
+			if der.probeSource(source) {
+				alive = append(alive, source)
+			}
+		}
+		if len(alive) == 0 {
+			continue
+		}
+		return alive
+	}
+	return []*Source{{URL: der.durl}}
 }
 
 //MustCheck 遇到错误则panic
@@ -65,7 +115,10 @@ func (der *Downloader) lazyInit() {
 	}
 	if der.monitor == nil {
 		der.monitor = &Monitor{
-			status: NewDownloadStatus(),
+			status:     NewDownloadStatus(),
+			durl:       der.durl,
+			cfg:        der.config,
+			taskBucket: ratelimit.NewBucket(der.config.RateLimit),
 		}
 	}
 }
@@ -75,6 +128,10 @@ func (der *Downloader) Execute() error {
 	der.lazyInit()
 	der.MustCheck()
 
+	// 解析候选下载地址: 没有配置 resolvers 时只有 der.durl 这一个候选
+	der.sources = der.resolveSources()
+	der.durl = der.sources[0].URL
+
 	// 检测
 	resp, err := der.client.Req("HEAD", der.durl, nil, nil)
 	if resp != nil {
@@ -109,6 +166,10 @@ func (der *Downloader) Execute() error {
 		referer = req.Referer()
 		durl = req.URL.String()
 	}
+	// 只有一个候选地址时(例如没有配置 SourceResolver, 或只有 PCSResolver 解析成功), 让它跟随 HEAD 请求可能发生的重定向
+	if len(der.sources) == 1 {
+		der.sources[0].URL = durl
+	}
 
 	//load breakpoint
 	err = der.initInstanceState()
@@ -168,15 +229,28 @@ func (der *Downloader) Execute() error {
 		writerAt = der.writer
 	}
 
+	// 全局限速桶(进程级, SetGlobalRateLimit 热更新)和本次任务的限速桶(Monitor.SetRateLimit
+	// 热更新)串联起来, Worker 读取响应体时会依次向两者申请令牌, 取两者中更严格的一个生效.
+	taskLimiter := ratelimit.Chain(globalRateLimitBucket, der.monitor.taskBucket)
+	der.monitor.writerAt = writerAt
+	der.monitor.sources = der.sources
+
 	workerInit := func(wer *Worker) {
 		wer.SetClient(der.client)
 		wer.SetCacheSize(der.config.cacheSize)
 		wer.SetWriteMutex(writeMu)
 		wer.SetReferer(referer)
+		wer.SetRateLimiter(taskLimiter)
 	}
+	// rebalance 新建 Worker(Monitor.pickStealWorker)时必须套用同一份初始化逻辑,
+	// 否则新 Worker 的 client 为 nil, Worker.Execute 一跑就会 panic.
+	der.monitor.SetWorkerInit(workerInit)
 
 	for i := 0; i < der.config.parallel; i++ {
-		worker = NewWorker(int32(i), durl, writerAt)
+		// 多个候选地址时, 不同 Worker 轮流分配到不同的镜像主机上, 形成跨主机的多源并行下载;
+		// 只有一个候选地址(默认情况)时, 所有 Worker 仍然打到同一个 durl 上, 行为不变.
+		workerURL := der.sources[i%len(der.sources)].URL
+		worker = NewWorker(int32(i), workerURL, writerAt)
 		workerInit(worker)
 
 		// 分配线程
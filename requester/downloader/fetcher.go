@@ -0,0 +1,73 @@
+package downloader
+
+import "errors"
+
+//FetcherEventType 任务事件类型
+type FetcherEventType int
+
+const (
+	//FetcherEventStarted 任务已开始
+	FetcherEventStarted FetcherEventType = iota
+	//FetcherEventProgress 进度更新
+	FetcherEventProgress
+	//FetcherEventPaused 任务已暂停
+	FetcherEventPaused
+	//FetcherEventResumed 任务已恢复
+	FetcherEventResumed
+	//FetcherEventError 任务出错
+	FetcherEventError
+	//FetcherEventFinished 任务已完成
+	FetcherEventFinished
+)
+
+//FetcherEvent 任务事件, 通过 Fetcher.Events 统一下发给调用方
+type FetcherEvent struct {
+	Type   FetcherEventType
+	Status DlStatus
+	Err    error
+}
+
+//FetchSpec 创建一个下载任务所需的参数, 不同的 Fetcher 实现按需取用
+type FetchSpec struct {
+	URL      string // http(s) 直链 / magnet 链接
+	FilePath string // .torrent / .metalink 本地文件路径, 为空则按 URL 处理
+	SavePath string // 保存路径
+
+	// 以下字段仅 HTTPFetcher 使用, 用来在 Cookie 方式之外叠加开放平台下载源,
+	// 详见 SourceResolver / OpenAPIResolver.
+	RemotePath    string // 网盘内的文件路径, 供 OpenAPIResolver 换取 dlink
+	AccessToken   string // 百度开放平台 access_token, 为空则不启用 OpenAPIResolver
+	PreferOpenAPI bool   // true 时优先尝试开放平台下载源, 再回退到 Cookie 方式
+}
+
+//ErrFetcherNotSupported Fetcher 不支持该种类型的任务
+var ErrFetcherNotSupported = errors.New("该 Fetcher 不支持此类型的下载任务")
+
+//Fetcher 协议无关的下载任务控制器.
+//
+//BaiduPCS-Go 内建的多线程 HTTP 下载引擎 (HTTPFetcher, 内部委托给 Downloader) 和基于
+//aria2 JSON-RPC 的下载引擎 (Aria2Fetcher) 都实现该接口, pcscommand 层只面向 Fetcher 编程,
+//普通直链和磁力链接/种子/metalink 任务共用同一套任务控制和事件接口.
+//磁力链接/种子/metalink 任务(download torrent)不进入网盘下载任务队列.
+type Fetcher interface {
+	//Create 根据 spec 创建(但不一定立即开始)一个下载任务
+	Create(spec *FetchSpec) error
+
+	//Start 开始/继续下载任务
+	Start() error
+
+	//Pause 暂停下载任务, 未实现暂停能力的 Fetcher 可返回 ErrFetcherNotSupported
+	Pause() error
+
+	//Resume 恢复已暂停的下载任务
+	Resume() error
+
+	//Cancel 取消下载任务, 并清理任务占用的资源(连接, 句柄等)
+	Cancel() error
+
+	//Status 返回当前任务的状态快照
+	Status() DlStatus
+
+	//Events 返回任务事件 channel, Fetcher 结束任务后必须关闭该 channel
+	Events() <-chan *FetcherEvent
+}
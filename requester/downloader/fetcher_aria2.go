@@ -0,0 +1,220 @@
+package downloader
+
+import (
+	"encoding/base64"
+	"errors"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/iikira/BaiduPCS-Go/requester/downloader/aria2"
+)
+
+//Aria2Config aria2 RPC 后端的连接信息
+type Aria2Config struct {
+	RPCURL string // 例如 http://127.0.0.1:6800/jsonrpc
+	Token  string // aria2 secret token
+}
+
+//Aria2Fetcher 基于 aria2 JSON-RPC 的 Fetcher 实现,
+//把 BT、磁力链接、离线(metalink)下载都甩给用户自己的 aria2 守护进程,
+//BaiduPCS-Go 只负责下发任务和轮询/订阅状态.
+type Aria2Fetcher struct {
+	client *aria2.Client
+	gid    string
+	events chan *FetcherEvent
+
+	pollInterval time.Duration
+}
+
+//NewAria2Fetcher 初始化 Aria2Fetcher
+func NewAria2Fetcher(cfg *Aria2Config) *Aria2Fetcher {
+	return &Aria2Fetcher{
+		client:       aria2.NewClient(cfg.RPCURL, cfg.Token),
+		events:       make(chan *FetcherEvent),
+		pollInterval: time.Second,
+	}
+}
+
+//Create 创建任务: magnet 链接和 http(s) 直链走 addUri; spec.FilePath 非空时表示是一个
+//本地 .torrent/.metalink 文件, 按扩展名分别走 addTorrent/addMetalink.
+func (f *Aria2Fetcher) Create(spec *FetchSpec) error {
+	if spec.FilePath == "" {
+		gid, err := f.client.AddURI([]string{spec.URL}, map[string]string{
+			"dir": spec.SavePath,
+		})
+		if err != nil {
+			return err
+		}
+		f.gid = gid
+		return nil
+	}
+
+	content, err := ioutil.ReadFile(spec.FilePath)
+	if err != nil {
+		return err
+	}
+	encoded := base64.StdEncoding.EncodeToString(content)
+
+	switch {
+	case strings.HasSuffix(spec.FilePath, ".torrent"):
+		uris := []string{}
+		if spec.URL != "" {
+			uris = append(uris, spec.URL)
+		}
+		gid, err := f.client.AddTorrent(encoded, uris, map[string]string{
+			"dir": spec.SavePath,
+		})
+		if err != nil {
+			return err
+		}
+		f.gid = gid
+		return nil
+	case strings.HasSuffix(spec.FilePath, ".metalink"):
+		gids, err := f.client.AddMetalink(encoded, map[string]string{
+			"dir": spec.SavePath,
+		})
+		if err != nil {
+			return err
+		}
+		if len(gids) == 0 {
+			return ErrFetcherNotSupported
+		}
+		f.gid = gids[0]
+		return nil
+	default:
+		return ErrFetcherNotSupported
+	}
+}
+
+//Start 开始下载任务, 通过 WebSocket 通知 + 轮询双保险获取状态更新
+func (f *Aria2Fetcher) Start() error {
+	notify, err := f.client.Subscribe(f.gid)
+	if err != nil {
+		// WebSocket 不可用时退化为纯轮询
+		notify = nil
+	}
+
+	go f.monitor(notify)
+
+	f.events <- &FetcherEvent{Type: FetcherEventStarted}
+	return nil
+}
+
+func (f *Aria2Fetcher) monitor(notify <-chan *aria2.Notification) {
+	ticker := time.NewTicker(f.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case n, ok := <-notify:
+			if !ok {
+				notify = nil
+				continue
+			}
+			if f.reportStatus(n.Method) {
+				return
+			}
+		case <-ticker.C:
+			if f.reportStatus("") {
+				return
+			}
+		}
+	}
+}
+
+// reportStatus 查询一次 aria2.tellStatus 并翻译为 FetcherEvent, 返回 true 表示任务已终结
+func (f *Aria2Fetcher) reportStatus(notifyMethod string) bool {
+	status, err := f.client.TellStatus(f.gid)
+	if err != nil {
+		f.events <- &FetcherEvent{Type: FetcherEventError, Err: err}
+		close(f.events)
+		return true
+	}
+
+	ds := aria2StatusToDlStatus(status)
+
+	switch {
+	case notifyMethod == "aria2.onDownloadError" || status.Status == "error":
+		errMsg := status.ErrorMessage
+		if errMsg == "" {
+			errMsg = "aria2 下载任务出错"
+		}
+		f.events <- &FetcherEvent{Type: FetcherEventError, Status: ds, Err: errors.New(errMsg)}
+		close(f.events)
+		return true
+	case notifyMethod == "aria2.onDownloadComplete" || notifyMethod == "aria2.onBtDownloadComplete" || status.Status == "complete":
+		f.events <- &FetcherEvent{Type: FetcherEventFinished, Status: ds}
+		close(f.events)
+		return true
+	case notifyMethod == "aria2.onDownloadPause" || status.Status == "paused":
+		f.events <- &FetcherEvent{Type: FetcherEventPaused, Status: ds}
+		return false
+	default:
+		f.events <- &FetcherEvent{Type: FetcherEventProgress, Status: ds}
+		return false
+	}
+}
+
+//Pause 暂停下载任务
+func (f *Aria2Fetcher) Pause() error {
+	return f.client.Pause(f.gid)
+}
+
+//Resume 恢复下载任务
+func (f *Aria2Fetcher) Resume() error {
+	return f.client.Unpause(f.gid)
+}
+
+//Cancel 取消下载任务
+func (f *Aria2Fetcher) Cancel() error {
+	return f.client.Remove(f.gid)
+}
+
+//Status 返回当前任务的状态快照
+func (f *Aria2Fetcher) Status() DlStatus {
+	status, err := f.client.TellStatus(f.gid)
+	if err != nil {
+		return NewDownloadStatus()
+	}
+	return aria2StatusToDlStatus(status)
+}
+
+//Events 返回任务事件 channel
+func (f *Aria2Fetcher) Events() <-chan *FetcherEvent {
+	return f.events
+}
+
+// aria2StatusToDlStatus 把 aria2.tellStatus 的字符串字段翻译为 DownloadStatus
+func aria2StatusToDlStatus(status *aria2.Status) *DownloadStatus {
+	ds := NewDownloadStatus()
+	ds.totalSize = parseInt64(status.TotalLength)
+	ds.downloaded = parseInt64(status.CompletedLength)
+	ds.speedsPerSecond = parseInt64(status.DownloadSpeed)
+	return ds
+}
+
+func parseInt64(s string) int64 {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+//PickFetcher 根据下载地址/本地文件选择合适的 Fetcher:
+//magnet 链接和 .torrent/.metalink 文件优先交给 aria2, 没有配置 aria2 时退化为内建引擎;
+//普通 http(s) 直链默认走内建引擎, 除非用户显式配置了 aria2 且 preferAria2 为 true.
+func PickFetcher(url string, aria2Cfg *Aria2Config, preferAria2 bool, config *Config) Fetcher {
+	isMagnetOrTorrent := strings.HasPrefix(url, "magnet:") || strings.HasSuffix(url, ".torrent") || strings.HasSuffix(url, ".metalink")
+
+	if aria2Cfg != nil && aria2Cfg.RPCURL != "" && (isMagnetOrTorrent || preferAria2) {
+		return NewAria2Fetcher(aria2Cfg)
+	}
+	return NewHTTPFetcher(config)
+}
@@ -0,0 +1,128 @@
+package downloader
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/iikira/BaiduPCS-Go/requester"
+	"github.com/iikira/BaiduPCS-Go/requester/rio"
+)
+
+//HTTPFetcher 内建的多线程 HTTP 下载引擎, 是 Fetcher 接口最原始的实现,
+//内部直接委托给 Downloader, 行为与重构前完全一致.
+type HTTPFetcher struct {
+	der    *Downloader
+	client *requester.HTTPClient
+	config *Config
+	events chan *FetcherEvent
+}
+
+//NewHTTPFetcher 初始化 HTTPFetcher
+func NewHTTPFetcher(config *Config) *HTTPFetcher {
+	if config == nil {
+		config = NewConfig()
+	}
+	return &HTTPFetcher{
+		config: config,
+		events: make(chan *FetcherEvent),
+	}
+}
+
+//SetClient 设置http客户端
+func (f *HTTPFetcher) SetClient(client *requester.HTTPClient) {
+	f.client = client
+}
+
+//Create 根据 spec 创建下载任务, spec.URL 必须是 http(s) 直链
+func (f *HTTPFetcher) Create(spec *FetchSpec) error {
+	var writer rio.WriteCloserAt
+	if !f.config.IsTest {
+		f.config.InstanceStatePath = spec.SavePath + ".BaiduPCS-Go-downloading"
+		os.MkdirAll(filepath.Dir(spec.SavePath), 0777)
+		file, err := os.OpenFile(spec.SavePath, os.O_CREATE|os.O_WRONLY, 0777)
+		if err != nil {
+			return err
+		}
+		writer = file
+	}
+
+	f.der = NewDownloader(spec.URL, writer, f.config)
+	if f.client != nil {
+		f.der.SetClient(f.client)
+	}
+	f.der.SetResolvers(buildResolvers(spec)...)
+	return nil
+}
+
+//buildResolvers 根据 FetchSpec 里的开放平台配置组装候选地址解析器列表:
+//没有配置 access_token 时只有 Cookie 方式这一个解析器, 行为和重构前一致.
+func buildResolvers(spec *FetchSpec) []SourceResolver {
+	pcs := NewPCSResolver(spec.URL, nil)
+	if spec.AccessToken == "" {
+		return []SourceResolver{pcs}
+	}
+
+	openAPI := NewOpenAPIResolver(spec.AccessToken)
+	openAPI.Path = spec.RemotePath
+
+	if spec.PreferOpenAPI {
+		return []SourceResolver{openAPI, pcs}
+	}
+	return []SourceResolver{pcs, openAPI}
+}
+
+//Start 开始下载任务, 并把 Downloader 的事件翻译为 FetcherEvent
+func (f *HTTPFetcher) Start() error {
+	f.der.OnExecute(func() {
+		f.events <- &FetcherEvent{Type: FetcherEventStarted}
+		ds := f.der.GetDownloadStatusChan()
+		for v := range ds {
+			f.events <- &FetcherEvent{Type: FetcherEventProgress, Status: v}
+		}
+	})
+	f.der.OnPause(func() {
+		f.events <- &FetcherEvent{Type: FetcherEventPaused}
+	})
+	f.der.OnResume(func() {
+		f.events <- &FetcherEvent{Type: FetcherEventResumed}
+	})
+	f.der.OnFinish(func() {
+		f.events <- &FetcherEvent{Type: FetcherEventFinished, Status: f.Status()}
+		close(f.events)
+	})
+
+	err := f.der.Execute()
+	if err != nil {
+		f.events <- &FetcherEvent{Type: FetcherEventError, Err: err}
+		close(f.events)
+	}
+	return err
+}
+
+//Pause 暂停下载任务
+func (f *HTTPFetcher) Pause() error {
+	f.der.Pause()
+	return nil
+}
+
+//Resume 恢复下载任务
+func (f *HTTPFetcher) Resume() error {
+	f.der.Resume()
+	return nil
+}
+
+//Cancel 取消下载任务
+func (f *HTTPFetcher) Cancel() error {
+	f.der.Cancel()
+	return nil
+}
+
+//Status 返回当前任务的状态快照
+func (f *HTTPFetcher) Status() DlStatus {
+	return f.der.monitor.status
+}
+
+//Events 返回任务事件 channel
+func (f *HTTPFetcher) Events() <-chan *FetcherEvent {
+	return f.events
+}
@@ -0,0 +1,80 @@
+package downloader
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+)
+
+//InstanceStateInfo 断点续传状态文件的内容: 已下载的进度和各 Worker 当前剩余的区间
+type InstanceStateInfo struct {
+	DlStatus *DownloadStatus
+	Ranges   []*Range
+}
+
+//InstanceState 断点续传状态文件的读写句柄, 路径由 Config.InstanceStatePath 指定
+type InstanceState struct {
+	path string
+	info *InstanceStateInfo
+}
+
+//Get 返回已加载的断点续传信息, 没有可用的断点信息时返回 nil
+func (is *InstanceState) Get() *InstanceStateInfo {
+	if is == nil {
+		return nil
+	}
+	return is.info
+}
+
+//Save 把当前进度和区间写入断点续传状态文件, 供下次启动时续传
+func (is *InstanceState) Save(status *DownloadStatus, ranges []*Range) {
+	if is == nil || is.path == "" {
+		return
+	}
+
+	info := &InstanceStateInfo{DlStatus: status, Ranges: ranges}
+	data, err := json.Marshal(info)
+	if err != nil {
+		return
+	}
+	ioutil.WriteFile(is.path, data, 0644)
+}
+
+//initInstanceState 加载(如果存在) der.config.InstanceStatePath 指向的断点续传状态文件.
+//测试下载或未指定路径时不启用断点续传.
+func (der *Downloader) initInstanceState() error {
+	if der.config.IsTest || der.config.InstanceStatePath == "" {
+		der.instanceState = &InstanceState{}
+		return nil
+	}
+
+	is := &InstanceState{path: der.config.InstanceStatePath}
+
+	data, err := ioutil.ReadFile(is.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			der.instanceState = is
+			return nil
+		}
+		return err
+	}
+
+	info := &InstanceStateInfo{}
+	if err = json.Unmarshal(data, info); err != nil {
+		// 状态文件损坏, 当作没有断点续传信息处理, 不阻塞下载
+		der.instanceState = is
+		return nil
+	}
+
+	is.info = info
+	der.instanceState = is
+	return nil
+}
+
+//removeInstanceState 下载完成后删除断点续传状态文件
+func (der *Downloader) removeInstanceState() {
+	if der.instanceState == nil || der.instanceState.path == "" {
+		return
+	}
+	os.Remove(der.instanceState.path)
+}
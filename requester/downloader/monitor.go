@@ -0,0 +1,198 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/iikira/BaiduPCS-Go/requester/ratelimit"
+)
+
+//Monitor 管理一个下载任务的所有 Worker: 启动/暂停/恢复它们, 汇总它们的状态到 DownloadStatus
+type Monitor struct {
+	mu      sync.Mutex
+	status  *DownloadStatus
+	workers []*Worker
+
+	completed     chan struct{}
+	instanceState *InstanceState
+
+	durl     string    // 默认下载地址, 没有配置 SourceResolver 时 sources 也只有这一个
+	sources  []*Source // 候选下载地址列表, rebalance 新建 Worker 时按轮询分配, 实现多源并行
+	writerAt io.WriterAt
+	cfg      *Config
+
+	taskBucket *ratelimit.Bucket // 当前任务的限速桶, SetRateLimit 热更新它
+
+	// initWorker 和 Downloader.Execute 里的 workerInit 是同一份初始化逻辑(设置
+	// client/cacheSize/写锁/referer/限速器), rebalance 新建 Worker 时必须同样调用一遍,
+	// 否则新 Worker 的 client 是 nil, Execute 一跑就会 panic.
+	initWorker func(wer *Worker)
+}
+
+//SetWorkerInit 设置新建 Worker 的初始化函数, 由 Downloader.Execute 在分配初始 Worker
+//之前调用一次, rebalance 通过 pickStealWorker 新建 Worker 时会复用同一份初始化逻辑.
+func (mon *Monitor) SetWorkerInit(initWorker func(wer *Worker)) {
+	mon.initWorker = initWorker
+}
+
+//Append 把一个 Worker 加入监控列表
+func (mon *Monitor) Append(wer *Worker) {
+	mon.mu.Lock()
+	mon.workers = append(mon.workers, wer)
+	mon.mu.Unlock()
+}
+
+//Workers 返回当前所有 Worker 的快照
+func (mon *Monitor) Workers() []*Worker {
+	mon.mu.Lock()
+	defer mon.mu.Unlock()
+	workers := make([]*Worker, len(mon.workers))
+	copy(workers, mon.workers)
+	return workers
+}
+
+//SetInstanceState 设置断点续传状态文件的读写句柄
+func (mon *Monitor) SetInstanceState(is *InstanceState) {
+	mon.instanceState = is
+}
+
+//SetRateLimit 热更新当前任务的限速(字节/秒), 不需要暂停/重建任务即可生效,
+//因为所有 Worker 共享同一个 taskBucket 实例. CLI 命令 download set-limit 调整的是
+//全局限速(SetGlobalRateLimit), 不经过这里.
+func (mon *Monitor) SetRateLimit(limit int64) {
+	if mon.taskBucket != nil {
+		mon.taskBucket.SetRate(limit)
+	}
+}
+
+//Execute 启动所有已加入的 Worker 并发下载, 同时定期汇总进度/落盘断点续传状态,
+//Config.DynamicSplit 开启时还会并行驱动慢分片的动态拆分.
+func (mon *Monitor) Execute(ctx context.Context) {
+	if mon.completed == nil {
+		mon.completed = make(chan struct{})
+	}
+
+	var wg sync.WaitGroup
+	runWorker := func(wer *Worker) {
+		defer wg.Done()
+		wer.Execute(ctx)
+	}
+
+	for _, wer := range mon.Workers() {
+		wg.Add(1)
+		go runWorker(wer)
+	}
+
+	statusDone := make(chan struct{})
+	go mon.trackStatus(ctx, statusDone)
+
+	if mon.cfg != nil && mon.cfg.DynamicSplit {
+		go mon.runRebalanceLoop(ctx, mon.cfg)
+	}
+
+	// rebalance 可能会在运行期间 Append 新 Worker, 所以要持续检查是否有新任务加入,
+	// 而不能只依赖启动时 Workers() 那一份快照.
+	watched := make(map[*Worker]bool)
+	for _, wer := range mon.Workers() {
+		watched[wer] = true
+	}
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+pollNewWorkers:
+	for {
+		select {
+		case <-ctx.Done():
+			break pollNewWorkers
+		case <-ticker.C:
+			allFinished := true
+			for _, wer := range mon.Workers() {
+				if !watched[wer] {
+					watched[wer] = true
+					wg.Add(1)
+					go runWorker(wer)
+				}
+				if !wer.Finished() {
+					allFinished = false
+				}
+			}
+			if allFinished {
+				break pollNewWorkers
+			}
+		}
+	}
+
+	wg.Wait()
+	close(statusDone)
+	close(mon.completed)
+}
+
+//trackStatus 每秒汇总一次所有 Worker 的下载量/速度到 mon.status, 并保存断点续传状态
+func (mon *Monitor) trackStatus(ctx context.Context, done <-chan struct{}) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-done:
+			return
+		case <-ticker.C:
+			mon.refreshStatus()
+		}
+	}
+}
+
+func (mon *Monitor) refreshStatus() {
+	var downloaded, speed int64
+	for _, wer := range mon.Workers() {
+		downloaded += wer.Downloaded()
+		speed += wer.Speed()
+	}
+	mon.status.downloaded = downloaded
+	mon.status.speedsPerSecond = speed
+
+	if mon.instanceState != nil {
+		mon.instanceState.Save(mon.status, mon.ranges())
+	}
+}
+
+func (mon *Monitor) ranges() []*Range {
+	workers := mon.Workers()
+	ranges := make([]*Range, 0, len(workers))
+	for _, wer := range workers {
+		wer.rangeMu.Lock()
+		if wer.wrange != nil {
+			ranges = append(ranges, &Range{Begin: wer.wrange.Begin + wer.Downloaded(), End: wer.wrange.End})
+		}
+		wer.rangeMu.Unlock()
+	}
+	return ranges
+}
+
+//Pause 暂停所有 Worker
+func (mon *Monitor) Pause() {
+	for _, wer := range mon.Workers() {
+		wer.Pause()
+	}
+}
+
+//Resume 恢复所有 Worker
+func (mon *Monitor) Resume() {
+	for _, wer := range mon.Workers() {
+		wer.Resume()
+	}
+}
+
+//ShowWorkers 返回所有 Worker 当前状态的可读描述, 用于调试输出
+func (mon *Monitor) ShowWorkers() string {
+	var s string
+	for _, wer := range mon.Workers() {
+		s += fmt.Sprintf("# Worker %d: %d/%d, %d B/s\n", wer.ID(), wer.Downloaded(), wer.RemainingSize()+wer.Downloaded(), wer.Speed())
+	}
+	return s
+}
@@ -0,0 +1,92 @@
+package downloader
+
+import (
+	"sync"
+	"time"
+)
+
+//progressCoalesceWindow Progress 事件每个订阅者每个任务最多推送一次的间隔,
+//避免慢速订阅者(如一个卡顿的 WebSocket 连接)被高频进度事件淹没.
+const progressCoalesceWindow = 500 * time.Millisecond
+
+//TaskEvent 是 EventNotifier 下发给订阅者的事件信封, 比 FetcherEvent 多了任务 ID,
+//因为一个 EventNotifier 要同时服务多个下载任务.
+type TaskEvent struct {
+	TaskID string
+	*FetcherEvent
+}
+
+//subscription 一个订阅者, taskID 为空字符串表示订阅所有任务的事件
+type subscription struct {
+	taskID       string
+	ch           chan *TaskEvent
+	lastProgress map[string]time.Time // 每个任务上次推送 Progress 事件的时间, 用于节流
+}
+
+//EventNotifier 发布/订阅式的任务事件分发器, 取代了一个 Downloader 只能有一个
+//GetDownloadStatusChan 消费者的限制: CLI 渲染器、taskstore 持久化 writer、
+//WebSocket 服务器都可以各自 Subscribe 同一批任务的事件.
+type EventNotifier struct {
+	mu   sync.Mutex
+	subs map[*subscription]struct{}
+}
+
+//NewEventNotifier 初始化一个 EventNotifier
+func NewEventNotifier() *EventNotifier {
+	return &EventNotifier{
+		subs: make(map[*subscription]struct{}),
+	}
+}
+
+//DefaultNotifier 进程内共享的默认事件分发器, pcscommand 的下载命令和 WebSocket
+//服务器都向它发布/订阅事件.
+var DefaultNotifier = NewEventNotifier()
+
+//Subscribe 订阅 taskID 的事件, taskID 为空字符串表示订阅所有任务.
+//返回的 channel 有缓冲区, 订阅者处理不及时时新事件会被丢弃而不会阻塞发布者;
+//调用返回的 unsubscribe 函数来取消订阅并关闭 channel.
+func (n *EventNotifier) Subscribe(taskID string) (ch <-chan *TaskEvent, unsubscribe func()) {
+	sub := &subscription{
+		taskID:       taskID,
+		ch:           make(chan *TaskEvent, 16),
+		lastProgress: make(map[string]time.Time),
+	}
+
+	n.mu.Lock()
+	n.subs[sub] = struct{}{}
+	n.mu.Unlock()
+
+	return sub.ch, func() {
+		n.mu.Lock()
+		delete(n.subs, sub)
+		n.mu.Unlock()
+		close(sub.ch)
+	}
+}
+
+//Publish 向所有匹配 taskID 的订阅者下发一个事件. Progress 事件按订阅者+任务维度
+//节流, 其余类型(Started/Paused/Resumed/Error/Finished)不节流. 没有单个 Worker 级别的事件类型.
+func (n *EventNotifier) Publish(taskID string, event *FetcherEvent) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	now := time.Now()
+	for sub := range n.subs {
+		if sub.taskID != "" && sub.taskID != taskID {
+			continue
+		}
+
+		if event.Type == FetcherEventProgress {
+			if last, ok := sub.lastProgress[taskID]; ok && now.Sub(last) < progressCoalesceWindow {
+				continue
+			}
+			sub.lastProgress[taskID] = now
+		}
+
+		select {
+		case sub.ch <- &TaskEvent{TaskID: taskID, FetcherEvent: event}:
+		default:
+			// 订阅者消费太慢, 丢弃本次事件而不阻塞发布者
+		}
+	}
+}
@@ -0,0 +1,15 @@
+package downloader
+
+//Range 描述一个下载分片占据的字节区间, 两端都是闭区间(包含 End 这一字节)
+type Range struct {
+	Begin int64
+	End   int64
+}
+
+//Len 返回区间包含的字节数
+func (r *Range) Len() int64 {
+	if r == nil || r.End < r.Begin {
+		return 0
+	}
+	return r.End - r.Begin + 1
+}
@@ -0,0 +1,50 @@
+package downloader
+
+import (
+	"io"
+
+	"github.com/iikira/BaiduPCS-Go/requester/ratelimit"
+)
+
+//RateLimitedReader 包装一个 io.Reader, 每次 Read 读到数据后先向限速器申请等量的令牌再返回,
+//Worker 的读取循环用它包一层 HTTP 响应体, 就能把分片写入缓存的速度限制住.
+type RateLimitedReader struct {
+	r       io.Reader
+	limiter interface {
+		Wait(n int64)
+	}
+}
+
+//NewRateLimitedReader 用 limiter 包装 r, limiter 为 nil 时退化为直接透传
+func NewRateLimitedReader(r io.Reader, limiter interface{ Wait(n int64) }) *RateLimitedReader {
+	return &RateLimitedReader{r: r, limiter: limiter}
+}
+
+//Read 实现 io.Reader, 读到 n 个字节后先向限速器申请 n 个令牌, 拿到令牌才返回
+func (rr *RateLimitedReader) Read(p []byte) (n int, err error) {
+	n, err = rr.r.Read(p)
+	if n > 0 && rr.limiter != nil {
+		rr.limiter.Wait(int64(n))
+	}
+	return
+}
+
+//SetRateLimit 热更新当前下载任务的限速(字节/秒), limit <= 0 表示取消限速.
+//不需要取消/重建任务即可生效. CLI 命令 `download set-limit <bytes>` 调整的是全局限速, 见 SetGlobalRateLimit.
+func (der *Downloader) SetRateLimit(limit int64) {
+	der.config.RateLimit = limit
+	if der.monitor != nil {
+		der.monitor.SetRateLimit(limit)
+	}
+}
+
+//SetGlobalRateLimit 热更新全局限速(字节/秒), 影响当前进程内所有正在运行的下载任务.
+//对应 CLI 命令 `download set-limit <bytes>`.
+func SetGlobalRateLimit(limit int64) {
+	globalRateLimitBucket.SetRate(limit)
+}
+
+// globalRateLimitBucket 进程级别的全局限速桶, 由 Downloader.Execute 和每个任务自己的
+// taskBucket(Config.RateLimit)串联(ratelimit.Chain)后生效, 取两者中更严格的一个.
+// 0 表示不限速.
+var globalRateLimitBucket = ratelimit.NewBucket(0)
@@ -0,0 +1,122 @@
+package downloader
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+//rebalanceInterval 重新评估各 Worker 进度的周期
+const rebalanceInterval = 2 * time.Second
+
+//rebalance 扫描当前所有 Worker 的瞬时吞吐量和剩余字节数, 计算出各自的预计剩余时间(ETA),
+//当某个 Worker 的 ETA 超过中位数 ETA 的 Config.SlowFactor 倍、且剩余字节仍大于
+//Config.MinSplitSize 时, 认为它是"拖后腿"的慢分片, 将其剩余区间从中间切开,
+//后半段交给一个新 Worker(parallel < MaxParallel 时)或当前最快的空闲/已完成 Worker 继续下载.
+//
+//只有 Config.DynamicSplit 为 true 时才会被 Monitor.Execute 的监控循环调用.
+func (mon *Monitor) rebalance(cfg *Config) {
+	workers := mon.Workers()
+	if len(workers) == 0 {
+		return
+	}
+
+	etas := make([]time.Duration, 0, len(workers))
+	for _, wer := range workers {
+		etas = append(etas, wer.ETA())
+	}
+	sort.Slice(etas, func(i, j int) bool { return etas[i] < etas[j] })
+	medianETA := etas[len(etas)/2]
+
+	for _, wer := range workers {
+		if wer.Finished() {
+			continue
+		}
+
+		eta := wer.ETA()
+		// 用 float64 比较: 中位数是"速度为 0"的哨兵值时, 乘以 SlowFactor 再转回 Duration 会溢出
+		if medianETA <= 0 || float64(eta) <= cfg.SlowFactor*float64(medianETA) {
+			continue
+		}
+		if wer.RemainingSize() <= cfg.MinSplitSize {
+			continue
+		}
+
+		// 先确定接收方再拆分, 否则没有接收方时被截掉的后半段就没人下载了
+		receiver := mon.pickStealWorker(cfg)
+		if receiver == nil {
+			continue
+		}
+
+		tail, err := wer.SplitTail()
+		if err != nil || tail == nil {
+			continue
+		}
+
+		// 已在监控列表里的 Worker 不会被 Monitor.Execute 再次启动, 用它的编号和地址新建一个接手
+		for _, existing := range mon.Workers() {
+			if existing == receiver {
+				receiver = NewWorker(existing.id, existing.url, mon.writerAt)
+				if mon.initWorker != nil {
+					mon.initWorker(receiver)
+				}
+				break
+			}
+		}
+		receiver.SetRange(wer.acceptRanges, tail)
+		mon.Append(receiver)
+	}
+}
+
+//pickStealWorker 返回用于接收被窃取区间的 Worker: 优先开一个新 Worker(若未超过 MaxParallel),
+//否则复用当前最快的空闲或已完成的 Worker.
+func (mon *Monitor) pickStealWorker(cfg *Config) *Worker {
+	workers := mon.Workers()
+	if len(workers) < cfg.MaxParallel {
+		sources := mon.sources
+		if len(sources) == 0 {
+			sources = []*Source{{URL: mon.durl}}
+		}
+		// 和 Downloader.Execute 里初始分配 Worker 时一样按轮询选择镜像主机,
+		// 这样 rebalance 新开的 Worker 也能用上多源并行, 而不是都挤到同一个主机上.
+		url := sources[len(workers)%len(sources)].URL
+		wer := NewWorker(int32(len(workers)), url, mon.writerAt)
+		// 必须套用和初始 Worker 一样的初始化(client/cacheSize/写锁/referer/限速器),
+		// 否则这里新建的 Worker client 为 nil, Execute 一跑就会 panic.
+		if mon.initWorker != nil {
+			mon.initWorker(wer)
+		}
+		return wer
+	}
+
+	var fastest *Worker
+	for _, wer := range workers {
+		if !wer.Finished() && !wer.Idle() {
+			continue
+		}
+		if fastest == nil || wer.Speed() > fastest.Speed() {
+			fastest = wer
+		}
+	}
+	return fastest
+}
+
+//runRebalanceLoop 以 rebalanceInterval 为周期调用 rebalance, 直到 ctx 被取消.
+//由 Monitor.Execute 在 Config.DynamicSplit 开启时与原有的状态轮询一起启动.
+func (mon *Monitor) runRebalanceLoop(ctx context.Context, cfg *Config) {
+	if !cfg.DynamicSplit {
+		return
+	}
+
+	ticker := time.NewTicker(rebalanceInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			mon.rebalance(cfg)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package downloader
+
+import "errors"
+
+//ErrNoSource 所有 SourceResolver 都未能解析出可用的下载地址
+var ErrNoSource = errors.New("未能解析出可用的下载地址")
+
+//Source 一个可下载的候选地址, 以及请求它所需要附带的请求头(如鉴权 token, Referer)
+type Source struct {
+	URL     string
+	Headers map[string]string
+}
+
+//SourceResolver 把一个网盘路径解析为一组候选下载地址.
+//
+//同一个文件可能存在多种取得下载直链的方式(cookie 方式, 开放平台方式等), 每种方式
+//各自实现一个 SourceResolver; Downloader.Execute 依次尝试, 一旦有 resolver 解析出
+//多个镜像地址, 不同的 Worker 会被分配到不同的地址上, 形成跨主机的多源并行下载.
+type SourceResolver interface {
+	Resolve(path string) ([]*Source, error)
+}
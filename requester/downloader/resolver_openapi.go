@@ -0,0 +1,126 @@
+package downloader
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+)
+
+const (
+	openAPIMetaURL     = "https://pan.baidu.com/rest/2.0/xpan/multimedia"
+	openAPIDownloadURL = "https://d.pcs.baidu.com/rest/2.0/pcs/file"
+)
+
+//OpenAPIResolver 基于百度开放平台(pan.baidu.com 开放接口)的下载地址解析方式,
+//用 access_token + fsid/path 代替 Cookie, 在 Cookie 方式被风控拦截时作为备用下载通道,
+//参考 jsyzchen/pan 的调用方式: 先用 filemetas 接口换取 dlink, 再用 dlink 实际下载.
+type OpenAPIResolver struct {
+	AccessToken string
+	FsID        string // 文件的 fs_id, 与 Path 二选一
+	Path        string // 网盘内的文件路径, 与 FsID 二选一
+
+	httpClient *http.Client
+}
+
+//NewOpenAPIResolver 初始化 OpenAPIResolver
+func NewOpenAPIResolver(accessToken string) *OpenAPIResolver {
+	return &OpenAPIResolver{
+		AccessToken: accessToken,
+		httpClient:  http.DefaultClient,
+	}
+}
+
+type openAPIMetaResponse struct {
+	ErrNo int `json:"errno"`
+	List  []struct {
+		FsID  int64  `json:"fs_id"`
+		Dlink string `json:"dlink"`
+	} `json:"list"`
+}
+
+//Resolve 实现 SourceResolver. path 参数在 r.Path 为空时会被当作网盘路径使用,
+//最终返回的 Source.URL 是已经附带了 access_token 的可直接下载地址.
+func (r *OpenAPIResolver) Resolve(path string) ([]*Source, error) {
+	if r.AccessToken == "" {
+		return nil, ErrNoSource
+	}
+	if r.Path == "" {
+		r.Path = path
+	}
+
+	query := url.Values{}
+	query.Set("method", "filemetas")
+	query.Set("access_token", r.AccessToken)
+	query.Set("dlink", "1")
+	if r.FsID != "" {
+		query.Set("fsids", fmt.Sprintf("[%s]", r.FsID))
+	} else {
+		query.Set("path", r.Path)
+	}
+
+	resp, err := r.httpClient.Get(openAPIMetaURL + "?" + query.Encode())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var meta openAPIMetaResponse
+	if err = json.Unmarshal(body, &meta); err != nil {
+		return nil, err
+	}
+	if meta.ErrNo != 0 || len(meta.List) == 0 {
+		return nil, ErrNoSource
+	}
+
+	sources := make([]*Source, 0, len(meta.List))
+	for _, item := range meta.List {
+		if item.Dlink == "" {
+			continue
+		}
+		// dlink 本身不带 access_token, 需要自行拼接才能直接下载
+		dlink, err := appendAccessToken(item.Dlink, r.AccessToken)
+		if err != nil {
+			continue
+		}
+		sources = append(sources, &Source{URL: dlink})
+	}
+	if len(sources) == 0 {
+		// dlink 不可用, 退化为直接按路径下载的接口
+		if r.Path == "" {
+			return nil, ErrNoSource
+		}
+		return []*Source{{URL: openAPIDownloadFileURL(r.AccessToken, r.Path)}}, nil
+	}
+	return sources, nil
+}
+
+// appendAccessToken 把 access_token 合并进 dlink 的查询参数里. dlink 是否已经带有
+// 其它查询参数(即是否含 "?")取决于具体返回的链接形态, 直接用 "&" 拼接在没有 "?" 时
+// 会拼出形如 "http://host/file&access_token=xxx" 的非法 URL, 必须用 net/url 正确合并.
+func appendAccessToken(dlink, accessToken string) (string, error) {
+	u, err := url.Parse(dlink)
+	if err != nil {
+		return "", err
+	}
+	query := u.Query()
+	query.Set("access_token", accessToken)
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// openAPIDownloadFileURL 拼出通过 /rest/2.0/pcs/file?method=download 直接按路径下载的地址,
+// 部分场景下 dlink 不可用时可以退化使用这个接口.
+func openAPIDownloadFileURL(accessToken, path string) string {
+	query := url.Values{}
+	query.Set("method", "download")
+	query.Set("access_token", accessToken)
+	query.Set("path", path)
+	return openAPIDownloadURL + "?" + query.Encode()
+}
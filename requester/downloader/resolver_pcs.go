@@ -0,0 +1,24 @@
+package downloader
+
+import "net/http/cookiejar"
+
+//PCSResolver 现有的基于 Cookie 鉴权的解析方式: durl 由 baidupcs.DownloadFunc 的调用方
+//(即百度网盘 web/pcs 接口) 提前取得, 这里只是把它包装成 SourceResolver, 始终只返回一个
+//候选地址, 用来保持与重构前完全一致的行为.
+type PCSResolver struct {
+	durl string
+	jar  *cookiejar.Jar
+}
+
+//NewPCSResolver 初始化 PCSResolver
+func NewPCSResolver(durl string, jar *cookiejar.Jar) *PCSResolver {
+	return &PCSResolver{durl: durl, jar: jar}
+}
+
+//Resolve 实现 SourceResolver, path 参数被忽略, 因为 durl 已经由调用方解析好
+func (r *PCSResolver) Resolve(path string) ([]*Source, error) {
+	if r.durl == "" {
+		return nil, ErrNoSource
+	}
+	return []*Source{{URL: r.durl}}, nil
+}
@@ -0,0 +1,114 @@
+package downloader
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestPCSResolverResolve(t *testing.T) {
+	r := NewPCSResolver("", nil)
+	if _, err := r.Resolve("/a"); err != ErrNoSource {
+		t.Fatalf("Resolve() with empty durl error = %v, want ErrNoSource", err)
+	}
+
+	r = NewPCSResolver("http://example.com/durl", nil)
+	sources, err := r.Resolve("/a")
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	if len(sources) != 1 || sources[0].URL != "http://example.com/durl" {
+		t.Fatalf("Resolve() = %+v, want one source with the configured durl", sources)
+	}
+}
+
+func TestOpenAPIResolverResolveNoAccessToken(t *testing.T) {
+	r := NewOpenAPIResolver("")
+	if _, err := r.Resolve("/a"); err != ErrNoSource {
+		t.Fatalf("Resolve() without access_token error = %v, want ErrNoSource", err)
+	}
+}
+
+func TestOpenAPIResolverResolveWithDlink(t *testing.T) {
+	r := NewOpenAPIResolver("token123")
+	r.httpClient = &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		body := `{"errno":0,"list":[{"fs_id":1,"dlink":"http://d.example.com/file"}]}`
+		return &http.Response{
+			StatusCode: 200,
+			Header:     make(http.Header),
+			Body:       io.NopCloser(strings.NewReader(body)),
+		}, nil
+	})}
+
+	sources, err := r.Resolve("/a/b")
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	if len(sources) != 1 || sources[0].URL != "http://d.example.com/file?access_token=token123" {
+		t.Fatalf("Resolve() = %+v, want dlink with access_token appended as a valid query parameter", sources)
+	}
+}
+
+func TestOpenAPIResolverResolveWithDlinkAlreadyHasQuery(t *testing.T) {
+	r := NewOpenAPIResolver("token123")
+	r.httpClient = &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		body := `{"errno":0,"list":[{"fs_id":1,"dlink":"http://d.example.com/file?a=1"}]}`
+		return &http.Response{
+			StatusCode: 200,
+			Header:     make(http.Header),
+			Body:       io.NopCloser(strings.NewReader(body)),
+		}, nil
+	})}
+
+	sources, err := r.Resolve("/a/b")
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	if len(sources) != 1 || sources[0].URL != "http://d.example.com/file?a=1&access_token=token123" {
+		t.Fatalf("Resolve() = %+v, want existing query preserved alongside access_token", sources)
+	}
+}
+
+func TestOpenAPIResolverResolveFallsBackWithoutDlink(t *testing.T) {
+	r := NewOpenAPIResolver("token123")
+	r.Path = "/a/b"
+	r.httpClient = &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		body := `{"errno":0,"list":[{"fs_id":1,"dlink":""}]}`
+		return &http.Response{
+			StatusCode: 200,
+			Header:     make(http.Header),
+			Body:       io.NopCloser(strings.NewReader(body)),
+		}, nil
+	})}
+
+	sources, err := r.Resolve("/a/b")
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	if len(sources) != 1 || !strings.Contains(sources[0].URL, openAPIDownloadURL) {
+		t.Fatalf("Resolve() = %+v, want fallback to the by-path download endpoint", sources)
+	}
+}
+
+func TestOpenAPIResolverResolveErrNo(t *testing.T) {
+	r := NewOpenAPIResolver("token123")
+	r.httpClient = &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		body := `{"errno":2,"list":[]}`
+		return &http.Response{
+			StatusCode: 200,
+			Header:     make(http.Header),
+			Body:       io.NopCloser(strings.NewReader(body)),
+		}, nil
+	})}
+
+	if _, err := r.Resolve("/a"); err != ErrNoSource {
+		t.Fatalf("Resolve() with non-zero errno = %v, want ErrNoSource", err)
+	}
+}
@@ -0,0 +1,45 @@
+package downloader
+
+import "time"
+
+//DlStatus 下载状态的只读视图, GetDownloadStatusChan/FetcherEvent 都通过这个接口
+//暴露进度, 而不是直接暴露 *DownloadStatus, 方便以后替换底层实现(如 aria2 的状态).
+type DlStatus interface {
+	TotalSize() int64
+	Downloaded() int64
+	SpeedsPerSecond() int64
+	TimeElapsed() time.Duration
+}
+
+//DownloadStatus 下载状态
+type DownloadStatus struct {
+	totalSize       int64
+	downloaded      int64
+	speedsPerSecond int64
+	timeElapsed     time.Duration
+}
+
+//NewDownloadStatus 初始化 DownloadStatus
+func NewDownloadStatus() *DownloadStatus {
+	return &DownloadStatus{}
+}
+
+//TotalSize 文件总大小
+func (ds *DownloadStatus) TotalSize() int64 {
+	return ds.totalSize
+}
+
+//Downloaded 已下载字节数
+func (ds *DownloadStatus) Downloaded() int64 {
+	return ds.downloaded
+}
+
+//SpeedsPerSecond 当前下载速度, 字节/秒
+func (ds *DownloadStatus) SpeedsPerSecond() int64 {
+	return ds.speedsPerSecond
+}
+
+//TimeElapsed 已耗费的时间
+func (ds *DownloadStatus) TimeElapsed() time.Duration {
+	return ds.timeElapsed
+}
@@ -0,0 +1,335 @@
+package downloader
+
+import (
+	"context"
+	"io"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/iikira/BaiduPCS-Go/requester"
+)
+
+type workerStatus int32
+
+const (
+	workerStatusIdle workerStatus = iota
+	workerStatusRunning
+	workerStatusPaused
+	workerStatusFinished
+	workerStatusError
+)
+
+//Worker 负责下载一个字节区间(wrange), 是 Downloader 并发下载的最小单位
+type Worker struct {
+	id       int32
+	url      string
+	writerAt io.WriterAt
+
+	client    *requester.HTTPClient
+	cacheSize int
+	writeMu   *sync.Mutex
+	referer   string
+
+	acceptRanges string
+	wrange       *Range
+
+	limiter interface{ Wait(n int64) }
+
+	status     int32 // workerStatus, 原子读写
+	downloaded int64 // 该 Worker 已下载的字节数, 原子读写
+	speed      int64 // 最近一次采样得到的瞬时速度, 字节/秒, 原子读写
+
+	pauseCh  chan struct{}
+	resumeCh chan struct{}
+
+	rangeMu sync.Mutex // 保护 wrange 在 SplitTail 时被并发读写
+
+	bodyMu     sync.Mutex    // 保护 curBody 在 abortCurrentRequest 时被并发读写
+	curBody    io.ReadCloser // 当前正在读取的响应体, SplitTail 截断区间时需要关闭它来中断读取
+	restarting int32         // 原子标记: abortCurrentRequest 主动中断了请求, Execute 需要用新区间重新发起, 而不是当作下载失败
+}
+
+//NewWorker 初始化一个 Worker, id 在同一个 Downloader 内唯一
+func NewWorker(id int32, url string, writerAt io.WriterAt) *Worker {
+	return &Worker{
+		id:       id,
+		url:      url,
+		writerAt: writerAt,
+		pauseCh:  make(chan struct{}, 1),
+		resumeCh: make(chan struct{}, 1),
+	}
+}
+
+//SetClient 设置http客户端
+func (wer *Worker) SetClient(client *requester.HTTPClient) {
+	wer.client = client
+}
+
+//SetCacheSize 设置读取缓存大小
+func (wer *Worker) SetCacheSize(size int) {
+	wer.cacheSize = size
+}
+
+//SetWriteMutex 设置写入互斥锁, 多个 Worker 共享同一个底层 writerAt 时用它防止并发写冲突
+func (wer *Worker) SetWriteMutex(mu *sync.Mutex) {
+	wer.writeMu = mu
+}
+
+//SetReferer 设置请求的 Referer
+func (wer *Worker) SetReferer(referer string) {
+	wer.referer = referer
+}
+
+//SetRange 设置该 Worker 负责下载的字节区间, acceptRanges 为空字符串表示服务端不支持分片
+func (wer *Worker) SetRange(acceptRanges string, r *Range) {
+	wer.acceptRanges = acceptRanges
+	wer.rangeMu.Lock()
+	wer.wrange = r
+	wer.rangeMu.Unlock()
+}
+
+//SetRateLimiter 设置限速器(通常是全局限速和任务限速串联后的 ratelimit.Chained), nil 表示不限速
+func (wer *Worker) SetRateLimiter(limiter interface{ Wait(n int64) }) {
+	wer.limiter = limiter
+}
+
+//ID 该 Worker 的编号
+func (wer *Worker) ID() int32 {
+	return wer.id
+}
+
+//Downloaded 该 Worker 已下载的字节数
+func (wer *Worker) Downloaded() int64 {
+	return atomic.LoadInt64(&wer.downloaded)
+}
+
+//Speed 最近一次采样得到的瞬时速度, 字节/秒
+func (wer *Worker) Speed() int64 {
+	return atomic.LoadInt64(&wer.speed)
+}
+
+//RemainingSize 该 Worker 负责区间里还没下载的字节数
+func (wer *Worker) RemainingSize() int64 {
+	wer.rangeMu.Lock()
+	defer wer.rangeMu.Unlock()
+	if wer.wrange == nil {
+		return 0
+	}
+	remaining := wer.wrange.Len() - wer.Downloaded()
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+//ETA 按当前速度估算剩余下载时间, 速度为 0 时返回一个很大的值, 避免被误判为"已完成"
+func (wer *Worker) ETA() time.Duration {
+	speed := wer.Speed()
+	if speed <= 0 {
+		return time.Duration(1<<62 - 1)
+	}
+	return time.Duration(float64(wer.RemainingSize())/float64(speed)) * time.Second
+}
+
+//Finished 该 Worker 是否已完成下载
+func (wer *Worker) Finished() bool {
+	return workerStatus(atomic.LoadInt32(&wer.status)) == workerStatusFinished
+}
+
+//Idle 该 Worker 是否尚未开始下载(用于 rebalance 时挑选可以接收被拆分区间的 Worker)
+func (wer *Worker) Idle() bool {
+	return workerStatus(atomic.LoadInt32(&wer.status)) == workerStatusIdle
+}
+
+//SplitTail 把该 Worker 尚未下载的剩余区间从中间切开, 前一半留给自己继续下载,
+//后一半以新的 Range 形式返回, 交给调用方(Monitor.rebalance)指派给另一个 Worker.
+//剩余字节数不足 2 时返回 nil, 表示不值得拆分.
+//
+//只把 wrange.End 截断是不够的: 如果该 Worker 正在 Execute 里读取一个已经发出去的
+//HTTP 响应, 它不会重新检查 wrange, 会继续把原本的区间读完, 截断形同虚设. 所以这里
+//还要调用 abortCurrentRequest 主动关闭当前响应体, 让 Execute 用截断后的区间重新
+//发起请求.
+func (wer *Worker) SplitTail() (*Range, error) {
+	wer.rangeMu.Lock()
+
+	if wer.wrange == nil {
+		wer.rangeMu.Unlock()
+		return nil, nil
+	}
+
+	downloaded := wer.Downloaded()
+	cur := wer.wrange.Begin + downloaded
+	remaining := wer.wrange.End - cur + 1
+	if remaining < 2 {
+		wer.rangeMu.Unlock()
+		return nil, nil
+	}
+
+	mid := cur + remaining/2
+	tail := &Range{Begin: mid, End: wer.wrange.End}
+	wer.wrange.End = mid - 1
+	wer.rangeMu.Unlock()
+
+	wer.abortCurrentRequest()
+	return tail, nil
+}
+
+//abortCurrentRequest 关闭该 Worker 当前正在读取的响应体(如果有), 解除阻塞在 Read 上的
+//读取, 并标记 restarting, 让 Execute 的外层循环据此识别出这是一次主动中断, 需要以
+//(可能已被 SplitTail 截断的)最新 wrange 重新发起请求, 而不是当作下载失败处理.
+func (wer *Worker) abortCurrentRequest() {
+	wer.bodyMu.Lock()
+	body := wer.curBody
+	if body != nil {
+		atomic.StoreInt32(&wer.restarting, 1)
+	}
+	wer.bodyMu.Unlock()
+
+	if body != nil {
+		body.Close()
+	}
+}
+
+//Pause 暂停该 Worker
+func (wer *Worker) Pause() {
+	atomic.StoreInt32(&wer.status, int32(workerStatusPaused))
+	select {
+	case wer.pauseCh <- struct{}{}:
+	default:
+	}
+}
+
+//Resume 恢复该 Worker
+func (wer *Worker) Resume() {
+	select {
+	case wer.resumeCh <- struct{}{}:
+	default:
+	}
+}
+
+//Execute 下载该 Worker 负责的字节区间, 直到完成或 ctx 被取消.
+//
+//拆成外层的重试循环加 runOnce: SplitTail 截断 wrange.End 时会调用 abortCurrentRequest
+//主动关闭 runOnce 当前持有的响应体, runOnce 因此带着 restarting 标记返回, 外层据此
+//重新调用 runOnce, 这时 runOnce 会按(已被截断的)最新 wrange 重新发起请求, 截断才能
+//真正提前结束这个 Worker 的下载, 而不是让它读完原本的整个区间.
+func (wer *Worker) Execute(ctx context.Context) error {
+	for {
+		atomic.StoreInt32(&wer.status, int32(workerStatusRunning))
+		err := wer.runOnce(ctx)
+		if atomic.CompareAndSwapInt32(&wer.restarting, 1, 0) {
+			continue
+		}
+		return err
+	}
+}
+
+//runOnce 发起一次 HTTP 请求并读取到 wrange.End(或被 abortCurrentRequest 中断)为止.
+//Range 请求头按 wrange.Begin+Downloaded() 计算, 这样即使是重启后的请求也只补下载
+//尚未写入的部分, 不会重复下载已经落盘的字节.
+func (wer *Worker) runOnce(ctx context.Context) error {
+	wer.rangeMu.Lock()
+	wrange := wer.wrange
+	wer.rangeMu.Unlock()
+
+	headers := map[string]string{}
+	if wer.referer != "" {
+		headers["Referer"] = wer.referer
+	}
+	if wer.acceptRanges == "bytes" && wrange != nil {
+		headers["Range"] = rangeHeader(&Range{Begin: wrange.Begin + wer.Downloaded(), End: wrange.End})
+	}
+
+	resp, err := wer.client.Req("GET", wer.url, nil, headers)
+	if err != nil {
+		atomic.StoreInt32(&wer.status, int32(workerStatusError))
+		return err
+	}
+
+	wer.bodyMu.Lock()
+	wer.curBody = resp.Body
+	wer.bodyMu.Unlock()
+	defer func() {
+		wer.bodyMu.Lock()
+		wer.curBody = nil
+		wer.bodyMu.Unlock()
+		resp.Body.Close()
+	}()
+
+	var body io.Reader = resp.Body
+	if wer.limiter != nil {
+		body = NewRateLimitedReader(body, wer.limiter)
+	}
+
+	cacheSize := wer.cacheSize
+	if cacheSize <= 0 {
+		cacheSize = 64 * 1024
+	}
+	buf := make([]byte, cacheSize)
+
+	offset := int64(0)
+	if wrange != nil {
+		offset = wrange.Begin
+	}
+
+	lastSampleTime := time.Now()
+	lastSampleDownloaded := wer.Downloaded()
+
+	for {
+		select {
+		case <-ctx.Done():
+			atomic.StoreInt32(&wer.status, int32(workerStatusPaused))
+			return ctx.Err()
+		case <-wer.pauseCh:
+			<-wer.resumeCh
+			atomic.StoreInt32(&wer.status, int32(workerStatusRunning))
+		default:
+		}
+
+		n, readErr := body.Read(buf)
+		if n > 0 {
+			writeAt := offset + wer.Downloaded()
+			if wer.writerAt != nil {
+				if wer.writeMu != nil {
+					wer.writeMu.Lock()
+				}
+				_, werr := wer.writerAt.WriteAt(buf[:n], writeAt)
+				if wer.writeMu != nil {
+					wer.writeMu.Unlock()
+				}
+				if werr != nil {
+					atomic.StoreInt32(&wer.status, int32(workerStatusError))
+					return werr
+				}
+			}
+
+			atomic.AddInt64(&wer.downloaded, int64(n))
+
+			if elapsed := time.Since(lastSampleTime); elapsed >= time.Second {
+				delta := wer.Downloaded() - lastSampleDownloaded
+				atomic.StoreInt64(&wer.speed, int64(float64(delta)/elapsed.Seconds()))
+				lastSampleTime = time.Now()
+				lastSampleDownloaded = wer.Downloaded()
+			}
+		}
+
+		if readErr != nil {
+			if readErr == io.EOF {
+				atomic.StoreInt32(&wer.status, int32(workerStatusFinished))
+				return nil
+			}
+			if atomic.LoadInt32(&wer.restarting) == 1 {
+				// abortCurrentRequest 主动关闭响应体触发的中断, 不是真正的下载失败
+				return readErr
+			}
+			atomic.StoreInt32(&wer.status, int32(workerStatusError))
+			return readErr
+		}
+	}
+}
+
+func rangeHeader(r *Range) string {
+	return "bytes=" + strconv.FormatInt(r.Begin, 10) + "-" + strconv.FormatInt(r.End, 10)
+}
@@ -0,0 +1,85 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBucketUnlimited(t *testing.T) {
+	b := NewBucket(0)
+	start := time.Now()
+	b.Wait(10 * 1024 * 1024)
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Fatalf("unlimited bucket should not block, waited %s", elapsed)
+	}
+}
+
+func TestBucketWaitConsumesCapacity(t *testing.T) {
+	b := NewBucket(1024)
+	b.Wait(1024) // 消耗满容量
+	start := time.Now()
+	b.Wait(512)
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+		t.Fatalf("expected Wait to block until refilled, only waited %s", elapsed)
+	}
+}
+
+func TestBucketSetRate(t *testing.T) {
+	b := NewBucket(1024)
+	if rate := b.Rate(); rate != 1024 {
+		t.Fatalf("Rate() = %d, want 1024", rate)
+	}
+
+	b.SetRate(0)
+	if rate := b.Rate(); rate != 0 {
+		t.Fatalf("Rate() after SetRate(0) = %d, want 0", rate)
+	}
+
+	start := time.Now()
+	b.Wait(10 * 1024 * 1024)
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Fatalf("Wait should not block after rate limit removed, waited %s", elapsed)
+	}
+}
+
+func TestChainUsesStrictestLimit(t *testing.T) {
+	loose := NewBucket(1024 * 1024)
+	strict := NewBucket(1024)
+	strict.Wait(1024) // 先消耗满, 确保下一次 Wait 一定要等待补充
+
+	chained := Chain(loose, strict)
+	start := time.Now()
+	chained.Wait(512)
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+		t.Fatalf("Chain should be limited by the stricter bucket, only waited %s", elapsed)
+	}
+}
+
+func TestBucketWaitRequestLargerThanCapacity(t *testing.T) {
+	b := NewBucket(1024)
+
+	done := make(chan struct{})
+	start := time.Now()
+	go func() {
+		b.Wait(3 * 1024) // 请求量是桶容量的 3 倍, refill 永远补不到这么多, 不能一直等下去
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		if elapsed := time.Since(start); elapsed < 1900*time.Millisecond {
+			t.Fatalf("expected Wait to consume in capacity-sized chunks and take ~2s, only waited %s", elapsed)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Wait(n) with n > capacity hung, want it to consume in capacity-sized chunks")
+	}
+}
+
+func TestChainIgnoresNilBuckets(t *testing.T) {
+	chained := Chain(nil, nil)
+	start := time.Now()
+	chained.Wait(10 * 1024 * 1024)
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Fatalf("Chain of nil buckets should not block, waited %s", elapsed)
+	}
+}